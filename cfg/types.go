@@ -3,30 +3,85 @@ package cfg
 type (
 	// Configuration holds a strongly-typed tree of the configuration
 	Configuration struct {
-		Namespace string
-		Git       GitConfig      `koanf:",squash"`
-		History   HistoryConfig  `koanf:",squash"`
-		Orphan    OrphanConfig   `koanf:",squash"`
-		Resource  ResourceConfig `koanf:",squash"`
-		Log       LogConfig
-		Delete    bool
+		Namespace   string
+		Git         GitConfig         `koanf:",squash"`
+		History     HistoryConfig     `koanf:",squash"`
+		Orphan      OrphanConfig      `koanf:",squash"`
+		Resource    ResourceConfig    `koanf:",squash"`
+		Registry    RegistryConfig    `koanf:",squash"`
+		Image       ImageConfig       `koanf:",squash"`
+		Generations GenerationsConfig `koanf:",squash"`
+		Usage       UsageConfig       `koanf:",squash"`
+		Log         LogConfig
+		Delete      bool
 	}
 	// GitConfig configures git repository
 	GitConfig struct {
-		CommitLimit  int    `koanf:"commit-limit"`
-		RepoPath     string `koanf:"repo-path"`
-		Tag          bool   `koanf:"tags"`
-		SortCriteria string `koanf:"sort"`
+		CommitLimit        int      `koanf:"commit-limit"`
+		RepoPath           string   `koanf:"repo-path"`
+		RepoURL            string   `koanf:"repo-url"`
+		RepoBranch         string   `koanf:"repo-branch"`
+		RepoUsername       string   `koanf:"repo-username"`
+		RepoPassword       string   `koanf:"repo-password"`
+		RepoSSHKey         string   `koanf:"repo-ssh-key"`
+		RepoSSHKeyPassword string   `koanf:"repo-ssh-key-password"`
+		Tag                bool     `koanf:"tags"`
+		SortCriteria       string   `koanf:"sort"`
+		Branches           []string `koanf:"branch"`
+		AllBranches        bool     `koanf:"all-branches"`
+		AllRemotes         bool     `koanf:"all-remotes"`
+		AllowShallow       bool     `koanf:"allow-shallow"`
+		TagInclude         []string `koanf:"git-tag-include"`
+		TagExclude         []string `koanf:"git-tag-exclude"`
+		RepoSubpaths       []string `koanf:"repo-subpath"`
+		MinSHALength       int      `koanf:"min-sha-length"`
 	}
 	// HistoryConfig configures the history command behaviour
 	HistoryConfig struct {
-		Keep int
+		Keep                     int
+		KeepMinors               int    `koanf:"keep-minors"`
+		KeepPatches              int    `koanf:"keep-patches"`
+		KeepLatestPerMajor       bool   `koanf:"keep-latest-per-major"`
+		DropPrereleasesOlderThan string `koanf:"drop-prereleases-older-than"`
+		KeepYoungerThan          string `koanf:"keep-younger-than"`
+		MaxAge                   string `koanf:"max-age"`
+		NoGit                    bool   `koanf:"no-git"`
+	}
+	// GenerationsConfig configures the generations command behaviour
+	GenerationsConfig struct {
+		KeepYoungerThan string `koanf:"keep-younger-than"`
 	}
 	// OrphanConfig configures the orphans command behaviour
 	OrphanConfig struct {
 		OlderThan           string `koanf:"older-than"`
 		OrphanDeletionRegex string `koanf:"deletion-pattern"`
 	}
+	// ImageConfig configures the behaviour shared by the image commands
+	ImageConfig struct {
+		All             bool     `koanf:"all"`
+		KeepPatterns    []string `koanf:"keep-pattern"`
+		ExcludePatterns []string `koanf:"exclude-pattern"`
+		TagPattern      string   `koanf:"tag-pattern"`
+	}
+	// UsageConfig configures where and in which resources the commands look for workloads using an image, ConfigMap
+	// or Secret
+	UsageConfig struct {
+		Namespaces      []string `koanf:"usage-namespaces"`
+		NamespaceLabels []string `koanf:"usage-namespace-label"`
+		AllNamespaces   bool     `koanf:"usage-all-namespaces"`
+		Resources       []string `koanf:"usage-resources"`
+		AddResources    []string `koanf:"usage-resources-add"`
+		RemoveResources []string `koanf:"usage-resources-remove"`
+		SubstringMatch  bool     `koanf:"usage-substring-match"`
+	}
+	// RegistryConfig configures the image registry backend
+	RegistryConfig struct {
+		Backend      string `koanf:"registry-backend"`
+		URL          string `koanf:"registry-url"`
+		DockerConfig string `koanf:"registry-config"`
+		Project      string `koanf:"registry-project"`
+		Token        string `koanf:"registry-token"`
+	}
 	// LogConfig configures the log
 	LogConfig struct {
 		LogLevel string `koanf:"level"`
@@ -45,13 +100,37 @@ type (
 func NewDefaultConfig() *Configuration {
 	return &Configuration{
 		Git: GitConfig{
-			CommitLimit:  0,
-			RepoPath:     ".",
-			Tag:          false,
-			SortCriteria: "version",
+			CommitLimit:        0,
+			RepoPath:           ".",
+			RepoURL:            "",
+			RepoBranch:         "",
+			RepoUsername:       "",
+			RepoPassword:       "",
+			RepoSSHKey:         "",
+			RepoSSHKeyPassword: "",
+			Tag:                false,
+			SortCriteria:       "version",
+			Branches:           []string{},
+			AllBranches:        false,
+			AllRemotes:         false,
+			AllowShallow:       false,
+			TagInclude:         []string{},
+			TagExclude:         []string{},
+			RepoSubpaths:       []string{},
+			MinSHALength:       7,
 		},
 		History: HistoryConfig{
-			Keep: 3,
+			Keep:                     3,
+			KeepMinors:               0,
+			KeepPatches:              1,
+			KeepLatestPerMajor:       false,
+			DropPrereleasesOlderThan: "",
+			KeepYoungerThan:          "",
+			MaxAge:                   "",
+			NoGit:                    false,
+		},
+		Generations: GenerationsConfig{
+			KeepYoungerThan: "",
 		},
 		Orphan: OrphanConfig{
 			OlderThan:           "1w",
@@ -62,6 +141,28 @@ func NewDefaultConfig() *Configuration {
 			OlderThan:   "1w",
 			DeleteAfter: "24h",
 		},
+		Usage: UsageConfig{
+			Namespaces:      []string{},
+			NamespaceLabels: []string{},
+			AllNamespaces:   false,
+			Resources:       []string{},
+			AddResources:    []string{},
+			RemoveResources: []string{},
+			SubstringMatch:  false,
+		},
+		Registry: RegistryConfig{
+			Backend:      "openshift",
+			URL:          "",
+			DockerConfig: "",
+			Project:      "",
+			Token:        "",
+		},
+		Image: ImageConfig{
+			All:             false,
+			KeepPatterns:    []string{},
+			ExcludePatterns: []string{},
+			TagPattern:      "",
+		},
 		Delete: false,
 		Log: LogConfig{
 			LogLevel: "info",
@@ -2,34 +2,52 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/cleanup"
 	"github.com/appuio/seiso/pkg/git"
+	"github.com/appuio/seiso/pkg/kubernetes"
 	"github.com/appuio/seiso/pkg/openshift"
+	"github.com/appuio/seiso/pkg/registry"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/thoas/go-funk"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-// DeleteImages deletes a list of image tags
-func DeleteImages(ctx context.Context, imageTags []string, imageName string, namespace string) {
+// DeleteImages deletes a list of image tags. Backends deleting manifests get all tags at once, so that they can keep
+// the manifests still referenced by other tags.
+func DeleteImages(ctx context.Context, backend registry.Backend, imageTags []string, imageName string, namespace string) {
+	if deleter, ok := backend.(registry.BatchDeleter); ok {
+		if err := deleter.DeleteImageTags(ctx, namespace, imageName, imageTags); err != nil {
+			log.WithError(err).Errorf("Failed to delete image tags of %s/%s", namespace, imageName)
+		}
+		return
+	}
 	for _, inactiveTag := range imageTags {
 		log.Infof("Deleting %s/%s:%s", namespace, imageName, inactiveTag)
 
-		if err := openshift.DeleteImageStreamTag(ctx, namespace, openshift.BuildImageStreamTagName(imageName, inactiveTag)); err != nil {
+		if err := backend.DeleteImageTag(ctx, namespace, imageName, inactiveTag); err != nil {
 			log.WithError(err).Errorf("Failed to delete %s/%s:%s", namespace, imageName, inactiveTag)
 		}
 	}
 }
 
-// PrintImageTags prints the given image tags line by line. In batch mode, only the tag name is printed, otherwise default
-// log with info level
+// PrintImageTags prints the given image tags line by line. In batch mode, only the tag name is printed (prefixed with
+// the image name if all images of the namespace are cleaned up), otherwise default log with info level
 func PrintImageTags(imageTags []string, imageName string, namespace string) {
 	if config.Log.Batch {
 		for _, tag := range imageTags {
-			fmt.Println(tag)
+			if config.Image.All {
+				fmt.Println(openshift.BuildImageStreamTagName(imageName, tag))
+			} else {
+				fmt.Println(tag)
+			}
 		}
 	} else {
 		for _, tag := range imageTags {
@@ -45,10 +63,359 @@ func addCommonFlagsForGit(cmd *cobra.Command, defaults *cfg.Configuration) {
 	cmd.PersistentFlags().IntP("commit-limit", "l", defaults.Git.CommitLimit,
 		"Only look at the first <l> commits to compare with tags. Use 0 (zero) for all commits. Limited effect if repo is a shallow clone.")
 	cmd.PersistentFlags().StringP("repo-path", "p", defaults.Git.RepoPath, "Path to Git repository")
+	cmd.PersistentFlags().StringArray("repo-subpath", defaults.Git.RepoSubpaths,
+		"Only compare with commits changing files in the path of the Git repository, e.g. \"services/api\". Can be given multiple times. Not effective with --tags")
+	cmd.PersistentFlags().String("repo-url", defaults.Git.RepoURL,
+		"URL of a remote Git repository to clone into memory instead of using --repo-path, e.g. https://git.example.com/app.git or ssh://git@git.example.com/app.git")
+	cmd.PersistentFlags().String("repo-branch", defaults.Git.RepoBranch,
+		"Only clone this branch of --repo-url instead of all branches")
+	cmd.PersistentFlags().String("repo-username", defaults.Git.RepoUsername,
+		"User name to clone --repo-url over HTTP(S)")
+	cmd.PersistentFlags().String("repo-password", defaults.Git.RepoPassword,
+		"Password or access token to clone --repo-url over HTTP(S), prefer setting it with SEISO_REPO_PASSWORD")
+	cmd.PersistentFlags().String("repo-ssh-key", defaults.Git.RepoSSHKey,
+		"Path to a private SSH key to clone --repo-url over SSH. The host key is verified with $SSH_KNOWN_HOSTS or ~/.ssh/known_hosts")
+	cmd.PersistentFlags().String("repo-ssh-key-password", defaults.Git.RepoSSHKeyPassword,
+		"Password of the private SSH key, prefer setting it with SEISO_REPO_SSH_KEY_PASSWORD")
 	cmd.PersistentFlags().BoolP("tags", "t", defaults.Git.Tag,
 		"Instead of comparing commit history, it will compare git tags with the existing image tags, removing any image tags that do not match")
 	cmd.PersistentFlags().String("sort", defaults.Git.SortCriteria,
-		fmt.Sprintf("Sort git tags by criteria. Only effective with --tags. Allowed values: [%s, %s]", git.SortOptionVersion, git.SortOptionAlphabetic))
+		fmt.Sprintf("Sort git tags by criteria. Only effective with --tags. Allowed values: [%s, %s, %s]", git.SortOptionVersion, git.SortOptionAlphabetic, git.SortOptionDate))
+	cmd.PersistentFlags().StringArray("branch", defaults.Git.Branches,
+		"Compare with the commits of the branches matching the glob pattern, e.g. \"release/*\", instead of HEAD. Can be given multiple times. Not effective with --tags")
+	cmd.PersistentFlags().Bool("all-branches", defaults.Git.AllBranches,
+		"Compare with the commits of all local branches instead of HEAD. Not effective with --tags")
+	cmd.PersistentFlags().Bool("all-remotes", defaults.Git.AllRemotes,
+		"Compare with the commits of all remote-tracking branches instead of HEAD. Not effective with --tags")
+	cmd.PersistentFlags().Int("min-sha-length", defaults.Git.MinSHALength,
+		"Ignore image tags (or SHAs extracted with --tag-pattern) shorter than the length when comparing with commit SHAs. Not effective with --tags")
+	cmd.PersistentFlags().Bool("allow-shallow", defaults.Git.AllowShallow,
+		"Allow deleting image tags even if the Git repository is a shallow clone with an incomplete history")
+	cmd.PersistentFlags().StringArray("git-tag-include", defaults.Git.TagInclude,
+		"Only compare with git tags whose full name matches the regex, e.g. \"^release/\". Can be given multiple times. Only effective with --tags")
+	cmd.PersistentFlags().StringArray("git-tag-exclude", defaults.Git.TagExclude,
+		"Ignore git tags whose full name matches the regex, e.g. \"-rc\\.[0-9]+$\". Can be given multiple times. Only effective with --tags")
+}
+
+// validateGitConfig checks the sort criteria and the git tag filter
+func validateGitConfig(c cfg.GitConfig) error {
+	if c.Tag && !git.IsValidSortValue(c.SortCriteria) {
+		return fmt.Errorf("invalid sort flag provided: %v", c.SortCriteria)
+	}
+	if c.MinSHALength < 4 || c.MinSHALength > 40 {
+		return fmt.Errorf("--min-sha-length must be between 4 and 40: %d", c.MinSHALength)
+	}
+	_, err := git.NewTagFilter(c.TagInclude, c.TagExclude)
+	return err
+}
+
+// checkShallowRepository warns if the Git repository is a shallow clone. If refuseDeletion is set, it returns an error
+// instead unless --allow-shallow is given.
+func checkShallowRepository(refuseDeletion bool) error {
+	shallow, err := git.IsShallow(&config.Git)
+	if err != nil {
+		return fmt.Errorf("could not open git repository: %w", err)
+	}
+	if !shallow {
+		return nil
+	}
+	if config.Git.AllowShallow {
+		log.Info("Git repository is a shallow clone, continuing because of --allow-shallow")
+		return nil
+	}
+	if refuseDeletion {
+		return errors.New("refusing to delete image tags because the Git repository is a shallow clone with an incomplete history. " +
+			"Fetch the full history (e.g. git fetch --unshallow) or use --allow-shallow")
+	}
+	log.Warn("Git repository is a shallow clone, image tags of commits missing in the history might be reported wrongly. " +
+		"Fetch the full history (e.g. git fetch --unshallow) or use --allow-shallow to suppress this warning")
+	return nil
+}
+
+// addCommonFlagsForImageSelection sets up the flags to clean up all image streams of a namespace at once
+func addCommonFlagsForImageSelection(cmd *cobra.Command, defaults *cfg.Configuration) {
+	cmd.PersistentFlags().Bool("all", defaults.Image.All,
+		"Clean up all image streams in the namespace instead of a single image. Only supported with the openshift registry backend")
+	cmd.PersistentFlags().StringSlice("label", defaults.Resource.Labels,
+		"Only clean up image streams with these \"key=value\" labels. Only effective with --all")
+}
+
+// addCommonFlagsForTagPatterns sets up the flags to protect or ignore image tags by their name
+func addCommonFlagsForTagPatterns(cmd *cobra.Command, defaults *cfg.Configuration) {
+	cmd.PersistentFlags().StringArray("keep-pattern", defaults.Image.KeepPatterns,
+		"Never delete image tags matching the regex, e.g. \"^(latest|stable)$\". Can be given multiple times")
+	cmd.PersistentFlags().StringArray("exclude-pattern", defaults.Image.ExcludePatterns,
+		"Ignore image tags matching the regex, e.g. \"^dev-\". Can be given multiple times")
+	cmd.PersistentFlags().String("tag-pattern", defaults.Image.TagPattern,
+		"Extract the git reference from image tags with the named groups sha, tag and branch of the regex, e.g. \"^(?P<branch>.+)-(?P<sha>[0-9a-f]{7,40})$\". "+
+			"Image tags not matching the regex are never deleted")
+}
+
+// parseTagPatterns compiles the given regular expressions
+func parseTagPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var regexps []*regexp.Regexp
+	for _, pattern := range patterns {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse tag pattern '%s': %w", pattern, err)
+		}
+		regexps = append(regexps, r)
+	}
+	return regexps, nil
+}
+
+// validateTagPatterns checks that the keep and exclude patterns are valid regular expressions
+func validateTagPatterns(c cfg.ImageConfig) error {
+	if _, err := parseTagPatterns(c.KeepPatterns); err != nil {
+		return err
+	}
+	if _, err := parseTagPatterns(c.ExcludePatterns); err != nil {
+		return err
+	}
+	if c.TagPattern == "" {
+		return nil
+	}
+	pattern, err := cleanup.NewTagPattern(c.TagPattern)
+	if err != nil {
+		return err
+	}
+	if config.Git.Tag && !pattern.HasGroup(cleanup.TagPatternGroupTag) {
+		return fmt.Errorf("--tag-pattern needs the named group %s with --tags", cleanup.TagPatternGroupTag)
+	}
+	if !config.Git.Tag && !pattern.HasGroup(cleanup.TagPatternGroupSHA) {
+		return fmt.Errorf("--tag-pattern needs the named group %s without --tags", cleanup.TagPatternGroupSHA)
+	}
+	return nil
+}
+
+// getTagMatcher returns how image tags are matched with the git candidates, along with the image tags to match. With
+// --tags, image tags match git tags by name or by the SHA of the tagged commit. With --tag-pattern, image tags the pattern cannot parse are reported and left out, so that they are never deleted. When
+// comparing with commits, image tags (or extracted SHAs) shorter than --min-sha-length are left out as well.
+func getTagMatcher(imageTags []string, imageName string) (cleanup.Matcher, []string, error) {
+	minLength := config.Git.MinSHALength
+	if config.Image.TagPattern == "" {
+		if config.Git.Tag {
+			commits, err := git.GetTagCommits(&config.Git)
+			if err != nil {
+				return nil, nil, err
+			}
+			return &cleanup.TagCommitMatcher{Commits: commits, MinLength: minLength}, imageTags, nil
+		}
+		imageTags, shortTags := cleanup.SplitShortTags(imageTags, minLength)
+		logShortTags(shortTags, imageName)
+		return cleanup.MatchOptionPrefix, imageTags, nil
+	}
+
+	pattern, _ := cleanup.NewTagPattern(config.Image.TagPattern)
+	matcher := &cleanup.PatternMatcher{Pattern: pattern, Tags: config.Git.Tag}
+	if !config.Git.Tag && pattern.HasGroup(cleanup.TagPatternGroupBranch) {
+		branches, err := git.GetBranchNames(&config.Git)
+		if err != nil {
+			return nil, nil, err
+		}
+		matcher.Branches = branches
+	}
+	parseableTags, unparseableTags := pattern.SplitParseable(imageTags)
+	if config.Git.Tag {
+		// the tag group may be optional in the pattern
+		parseableTags = funk.FilterString(parseableTags, func(tag string) bool {
+			ref, _ := pattern.Parse(tag)
+			if ref.Tag == "" {
+				unparseableTags = append(unparseableTags, tag)
+			}
+			return ref.Tag != ""
+		})
+	}
+	for _, tag := range unparseableTags {
+		log.WithField("imageTag", openshift.BuildImageStreamTagName(imageName, tag)).Warn("Image tag is unparseable by --tag-pattern and is never deleted")
+	}
+	if config.Git.Tag {
+		return matcher, parseableTags, nil
+	}
+	var shortTags []string
+	imageTags = funk.FilterString(parseableTags, func(tag string) bool {
+		ref, _ := pattern.Parse(tag)
+		if len(ref.SHA) < minLength {
+			shortTags = append(shortTags, tag)
+			return false
+		}
+		return true
+	})
+	logShortTags(shortTags, imageName)
+	return matcher, imageTags, nil
+}
+
+func logShortTags(shortTags []string, imageName string) {
+	if len(shortTags) > 0 {
+		log.WithFields(log.Fields{
+			"image":     imageName,
+			"imageTags": shortTags,
+		}).Debugf("Ignoring image tags with SHAs shorter than --min-sha-length=%d", config.Git.MinSHALength)
+	}
+}
+
+// removeAmbiguousTags removes the image tags matching several git candidates, e.g. short SHAs being a prefix of
+// several commits, so that they are never deleted
+func removeAmbiguousTags(gitCandidates, imageTags []string, matcher cleanup.Matcher, imageName string) []string {
+	ambiguousTags := cleanup.FindAmbiguousTags(gitCandidates, imageTags, matcher)
+	for _, tag := range ambiguousTags {
+		log.WithField("imageTag", openshift.BuildImageStreamTagName(imageName, tag)).Warn("Image tag matches several commits and is protected")
+	}
+	return cleanup.GetInactiveImageTags(&ambiguousTags, &imageTags)
+}
+
+// filterTagsByPatterns removes the tags matching the exclude or keep patterns. Tags protected by a keep pattern are
+// reported, excluded tags are silently ignored.
+func filterTagsByPatterns(imageTags []string, imageName string) []string {
+	excludePatterns, _ := parseTagPatterns(config.Image.ExcludePatterns)
+	keepPatterns, _ := parseTagPatterns(config.Image.KeepPatterns)
+
+	excludedTags, imageTags := cleanup.SplitByPatterns(imageTags, excludePatterns)
+	if len(excludedTags) > 0 {
+		log.WithFields(log.Fields{
+			"image":     imageName,
+			"imageTags": excludedTags,
+		}).Debug("Ignoring image tags excluded by pattern")
+	}
+	protectedTags, imageTags := cleanup.SplitByPatterns(imageTags, keepPatterns)
+	for _, tag := range protectedTags {
+		log.WithField("imageTag", openshift.BuildImageStreamTagName(imageName, tag)).Info("Image tag is protected by keep pattern")
+	}
+	return imageTags
+}
+
+// addCommonFlagsForUsage sets up the flags that select the namespaces in which workloads are checked for image usage
+func addCommonFlagsForUsage(cmd *cobra.Command, defaults *cfg.Configuration) {
+	cmd.PersistentFlags().StringSlice("usage-namespaces", defaults.Usage.Namespaces,
+		"Additional namespaces in which workloads are checked for usage of image tags")
+	cmd.PersistentFlags().StringSlice("usage-namespace-label", defaults.Usage.NamespaceLabels,
+		"Additionally check workloads in namespaces with these \"key=value\" labels for usage of image tags")
+	cmd.PersistentFlags().Bool("usage-all-namespaces", defaults.Usage.AllNamespaces,
+		"Check workloads in all namespaces of the cluster for usage of image tags")
+	addCommonFlagsForUsageResources(cmd, defaults)
+}
+
+// addCommonFlagsForUsageResources sets up the flags that select the kind of resources which are checked for usage and
+// how references are matched
+func addCommonFlagsForUsageResources(cmd *cobra.Command, defaults *cfg.Configuration) {
+	var defaultResources []string
+	for _, resource := range openshift.DefaultResources {
+		defaultResources = append(defaultResources, kubernetes.FormatResource(resource))
+	}
+	cmd.PersistentFlags().StringSlice("usage-resources", defaults.Usage.Resources,
+		fmt.Sprintf("Replace the resources that are checked for usage, given as \"resource.version.group\" (default [%s])", strings.Join(defaultResources, ",")))
+	cmd.PersistentFlags().StringSlice("usage-resources-add", defaults.Usage.AddResources,
+		"Additional resources that are checked for usage, e.g. \"services.v1.serving.knative.dev\"")
+	cmd.PersistentFlags().StringSlice("usage-resources-remove", defaults.Usage.RemoveResources,
+		"Resources that are not checked for usage, e.g. \"cronjobs.v1.batch\"")
+	cmd.PersistentFlags().Bool("usage-substring-match", defaults.Usage.SubstringMatch,
+		"Consider anything used that is contained in any string value of a resource, instead of matching image references and names exactly. Prone to false positives")
+}
+
+// validateUsageConfig checks the format of the namespace labels and applies the configured usage resources
+func validateUsageConfig(c cfg.UsageConfig) error {
+	for _, label := range c.NamespaceLabels {
+		if !strings.Contains(label, "=") {
+			return fmt.Errorf("incorrect namespace label format does not match expected \"key=value\" format: %s", label)
+		}
+	}
+	return applyUsageConfig(c)
+}
+
+// applyUsageConfig sets the resources that are checked for usage and the matching mode. The resources are the
+// default resources or the ones given with --usage-resources, extended by --usage-resources-add and without
+// --usage-resources-remove.
+func applyUsageConfig(c cfg.UsageConfig) error {
+	resources := openshift.DefaultResources
+	if len(c.Resources) > 0 {
+		parsed, err := kubernetes.ParseResources(c.Resources)
+		if err != nil {
+			return err
+		}
+		resources = parsed
+	}
+	added, err := kubernetes.ParseResources(c.AddResources)
+	if err != nil {
+		return err
+	}
+	removed, err := kubernetes.ParseResources(c.RemoveResources)
+	if err != nil {
+		return err
+	}
+	var result []schema.GroupVersionResource
+	for _, resource := range append(append([]schema.GroupVersionResource{}, resources...), added...) {
+		if funk.Contains(removed, resource) || funk.Contains(result, resource) {
+			continue
+		}
+		result = append(result, resource)
+	}
+	for _, resource := range removed {
+		if !funk.Contains(resources, resource) && !funk.Contains(added, resource) && !funk.Contains(openshift.ImageUsageResources, resource) {
+			log.WithField("resource", kubernetes.FormatResource(resource)).Warn("Resource to remove is not checked for usage anyway")
+		}
+	}
+	if len(result) == 0 {
+		return errors.New("no resources left to check for usage, adjust --usage-resources-remove")
+	}
+	log.WithField("resources", funk.Map(result, kubernetes.FormatResource)).Debug("Checking resources for usage")
+	openshift.PredefinedResources = result
+	openshift.RemovedResources = removed
+	openshift.SubstringMatching = c.SubstringMatch
+	return nil
+}
+
+// getUsageNamespaces returns the namespace of the images and all other namespaces that should be checked for usage of
+// the images. With --usage-all-namespaces, this is metav1.NamespaceAll only, so that each resource is listed once for
+// the whole cluster.
+func getUsageNamespaces(ctx context.Context, namespace string) ([]string, error) {
+	c := config.Usage
+	if c.AllNamespaces {
+		log.Debug("Checking all namespaces for image usage")
+		return []string{metav1.NamespaceAll}, nil
+	}
+	namespaces := append([]string{namespace}, c.Namespaces...)
+	if len(c.NamespaceLabels) > 0 {
+		coreClient, err := kubernetes.NewCoreV1Client()
+		if err != nil {
+			return nil, fmt.Errorf("cannot initiate kubernetes client: %w", err)
+		}
+		namespaceList, err := coreClient.Namespaces().List(ctx, toListOptions(c.NamespaceLabels))
+		if err != nil {
+			return nil, fmt.Errorf("could not list namespaces to check for image usage: %w", err)
+		}
+		for _, ns := range namespaceList.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+	namespaces = funk.UniqString(namespaces)
+	log.WithField("namespaces", namespaces).Debug("Checking namespaces for image usage")
+	return namespaces, nil
+}
+
+// addCommonFlagsForRegistry sets up the flags to select and configure the registry backend
+func addCommonFlagsForRegistry(cmd *cobra.Command, defaults *cfg.Configuration) {
+	cmd.PersistentFlags().String("registry-backend", defaults.Registry.Backend,
+		fmt.Sprintf("Registry backend to list and delete image tags. Allowed values: [%s, %s, %s, %s]",
+			registry.BackendOptionOpenShift, registry.BackendOptionDockerV2, registry.BackendOptionHarbor, registry.BackendOptionGitLab))
+	cmd.PersistentFlags().String("registry-url", defaults.Registry.URL,
+		"URL of the registry, e.g. https://registry.example.com. Required for all backends except openshift")
+	cmd.PersistentFlags().String("registry-config", defaults.Registry.DockerConfig,
+		"Path to a docker config.json file holding the registry credentials (default \"$DOCKER_CONFIG/config.json\" or \"~/.docker/config.json\")")
+	cmd.PersistentFlags().String("registry-project", defaults.Registry.Project,
+		"GitLab project ID owning the container registry. Defaults to $CI_PROJECT_ID")
+	cmd.PersistentFlags().String("registry-token", defaults.Registry.Token,
+		"GitLab personal or project access token. Defaults to the job token in $CI_JOB_TOKEN, prefer setting it with SEISO_REGISTRY_TOKEN")
+}
+
+// validateRegistryConfig checks that the selected registry backend exists and has its required settings
+func validateRegistryConfig(c cfg.RegistryConfig) error {
+	if !registry.IsValidBackendValue(c.Backend) {
+		return fmt.Errorf("invalid registry backend provided: %v", c.Backend)
+	}
+	if registry.BackendOption(c.Backend) != registry.BackendOptionOpenShift && c.URL == "" {
+		return fmt.Errorf("--registry-url is required for registry backend %s", c.Backend)
+	}
+	return nil
 }
 
 // toListOptions converts "key=value"-labels to Kubernetes LabelSelector
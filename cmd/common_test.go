@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/cleanup"
+	"github.com/appuio/seiso/pkg/kubernetes"
+	"github.com/appuio/seiso/pkg/openshift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thoas/go-funk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_applyUsageConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		usage         cfg.UsageConfig
+		wantResources []string
+		wantErr       bool
+	}{
+		{
+			name:  "ShouldUseDefaultResources_IfNothingConfigured",
+			usage: cfg.UsageConfig{},
+			wantResources: []string{"pods.v1", "statefulsets.v1.apps", "deployments.v1.apps", "daemonsets.v1.apps",
+				"replicasets.v1.apps", "deploymentconfigs.v1.apps.openshift.io", "cronjobs.v1.batch"},
+		},
+		{
+			name: "ShouldAddAndRemoveResources",
+			usage: cfg.UsageConfig{
+				AddResources:    []string{"services.v1.serving.knative.dev", "pods.v1"},
+				RemoveResources: []string{"deploymentconfigs.v1.apps.openshift.io", "cronjobs.v1.batch"},
+			},
+			wantResources: []string{"pods.v1", "statefulsets.v1.apps", "deployments.v1.apps", "daemonsets.v1.apps",
+				"replicasets.v1.apps", "services.v1.serving.knative.dev"},
+		},
+		{
+			name: "ShouldReplaceResources",
+			usage: cfg.UsageConfig{
+				Resources:    []string{"deployments.v1.apps"},
+				AddResources: []string{"rollouts.v1alpha1.argoproj.io"},
+			},
+			wantResources: []string{"deployments.v1.apps", "rollouts.v1alpha1.argoproj.io"},
+		},
+		{
+			name: "ShouldThrowError_IfResourceInvalid",
+			usage: cfg.UsageConfig{
+				AddResources: []string{"rollouts"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfAllResourcesRemoved",
+			usage: cfg.UsageConfig{
+				Resources:       []string{"pods.v1"},
+				RemoveResources: []string{"pods.v1"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				openshift.PredefinedResources = openshift.DefaultResources
+				openshift.SubstringMatching = false
+				openshift.RemovedResources = nil
+			}()
+
+			err := applyUsageConfig(tt.usage)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantResources, funk.Map(openshift.PredefinedResources, kubernetes.FormatResource))
+		})
+	}
+}
+
+func Test_filterTagsByPatterns(t *testing.T) {
+	config = cfg.NewDefaultConfig()
+	config.Image.KeepPatterns = []string{"^latest$", "^prod-"}
+	config.Image.ExcludePatterns = []string{"^dev-"}
+
+	tags := filterTagsByPatterns([]string{"latest", "dev-a3d0df2", "a3d0df2", "prod-1"}, "app")
+
+	assert.Equal(t, []string{"a3d0df2"}, tags)
+}
+
+func Test_validateTagPatterns(t *testing.T) {
+	config = cfg.NewDefaultConfig()
+	assert.NoError(t, validateTagPatterns(cfg.ImageConfig{KeepPatterns: []string{"^a{1,2}$"}}))
+	assert.Error(t, validateTagPatterns(cfg.ImageConfig{ExcludePatterns: []string{"("}}))
+	assert.NoError(t, validateTagPatterns(cfg.ImageConfig{TagPattern: "^main-(?P<sha>[0-9a-f]+)$"}))
+	assert.Error(t, validateTagPatterns(cfg.ImageConfig{TagPattern: "^(?P<tag>v.+)-build$"}))
+
+	config.Git.Tag = true
+	assert.NoError(t, validateTagPatterns(cfg.ImageConfig{TagPattern: "^(?P<tag>v.+)-build$"}))
+	assert.Error(t, validateTagPatterns(cfg.ImageConfig{TagPattern: "^main-(?P<sha>[0-9a-f]+)$"}))
+}
+
+func Test_getTagMatcher(t *testing.T) {
+	config = cfg.NewDefaultConfig()
+	config.Image.TagPattern = "^main-(?P<sha>[0-9a-f]+)$"
+
+	matcher, imageTags, err := getTagMatcher([]string{"main-a3d0df2", "latest", "main-b3d0df2"}, "app")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main-a3d0df2", "main-b3d0df2"}, imageTags)
+	assert.True(t, matcher.Match("main-a3d0df2", "a3d0df2c5060b87650df6a94a0a9600510303003"))
+}
+
+func Test_getUsageNamespaces(t *testing.T) {
+	config = cfg.NewDefaultConfig()
+	config.Usage.Namespaces = []string{"app-prod", "app-ci"}
+
+	namespaces, err := getUsageNamespaces(context.Background(), "app-ci")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app-ci", "app-prod"}, namespaces)
+
+	config.Usage.AllNamespaces = true
+	namespaces, err = getUsageNamespaces(context.Background(), "app-ci")
+	require.NoError(t, err)
+	assert.Equal(t, []string{metav1.NamespaceAll}, namespaces)
+}
+
+func Test_checkShallowRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seiso-shallow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".git", "shallow"), []byte{}, 0644))
+
+	tests := []struct {
+		name           string
+		allowShallow   bool
+		refuseDeletion bool
+		wantErr        bool
+	}{
+		{
+			name: "ShouldWarn_IfNotDeleting",
+		},
+		{
+			name:           "ShouldThrowError_IfDeleting",
+			refuseDeletion: true,
+			wantErr:        true,
+		},
+		{
+			name:           "ShouldAccept_IfShallowAllowed",
+			allowShallow:   true,
+			refuseDeletion: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config = cfg.NewDefaultConfig()
+			config.Git.RepoPath = dir
+			config.Git.AllowShallow = tt.allowShallow
+
+			err := checkShallowRepository(tt.refuseDeletion)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_getTagMatcher_MinSHALength(t *testing.T) {
+	tests := []struct {
+		name       string
+		tagPattern string
+		imageTags  []string
+		want       []string
+	}{
+		{
+			name:      "ShouldIgnoreShortTags",
+			imageTags: []string{"a3d0df2", "a3d0d", "latest"},
+			want:      []string{"a3d0df2"},
+		},
+		{
+			name:       "ShouldIgnoreShortExtractedSHAs",
+			tagPattern: "^main-(?P<sha>[0-9a-f]+)$",
+			imageTags:  []string{"main-a3d0df2", "main-a3d0d"},
+			want:       []string{"main-a3d0df2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config = cfg.NewDefaultConfig()
+			config.Image.TagPattern = tt.tagPattern
+
+			_, imageTags, err := getTagMatcher(tt.imageTags, "app")
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, imageTags)
+		})
+	}
+}
+
+func Test_removeAmbiguousTags(t *testing.T) {
+	config = cfg.NewDefaultConfig()
+	gitCandidates := []string{
+		"a3d0df2c5060b87650df6a94a0a9600510303003",
+		"a3d0df2f590ed7ed8be6ec0a2a87816228a482c9",
+	}
+
+	result := removeAmbiguousTags(gitCandidates, []string{"a3d0df2", "a3d0df2c"}, cleanup.MatchOptionPrefix, "app")
+
+	assert.Equal(t, []string{"a3d0df2c"}, result)
+}
+
+func Test_validateGitConfig(t *testing.T) {
+	assert.NoError(t, validateGitConfig(cfg.NewDefaultConfig().Git))
+	assert.Error(t, validateGitConfig(cfg.GitConfig{MinSHALength: 3}))
+	assert.Error(t, validateGitConfig(cfg.GitConfig{MinSHALength: 41}))
+	assert.Error(t, validateGitConfig(cfg.GitConfig{MinSHALength: 7, TagInclude: []string{"("}}))
+}
@@ -40,6 +40,7 @@ func init() {
 		"Keep most current <k> ConfigMaps; does not include currently used ConfigMaps (if detected)")
 	configMapCmd.PersistentFlags().String("older-than", defaults.Resource.OlderThan,
 		"Delete ConfigMaps that are older than the duration, e.g. [1y2mo3w4d5h6m7s]")
+	addCommonFlagsForUsageResources(configMapCmd, defaults)
 }
 
 func validateConfigMapCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
@@ -55,7 +56,7 @@ func validateConfigMapCommandInput(cmd *cobra.Command, args []string) (returnErr
 	if _, err := parseCutOffDateTime(config.Resource.OlderThan); err != nil {
 		return fmt.Errorf("could not parse older-than flag: %w", err)
 	}
-	return nil
+	return applyUsageConfig(config.Usage)
 }
 
 func executeConfigMapCleanupCommand(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/cleanup"
+	"github.com/appuio/seiso/pkg/openshift"
+	imagev1 "github.com/openshift/api/image/v1"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/thoas/go-funk"
+)
+
+const (
+	generationsCommandLongDescription = `Every image stream tag keeps a history of the images it pointed to, and each of these generations
+prevents the image from being pruned in the registry.
+This command removes old generations from the history of each tag. The current generation of a tag and generations
+whose image is referenced by a workload are never removed.`
+)
+
+var (
+	generationsCmd = &cobra.Command{
+		Use:          "generations [NAMESPACE/IMAGE]",
+		Short:        "Clean up old generations of image stream tags",
+		Long:         generationsCommandLongDescription,
+		Aliases:      []string{"gen", "generation"},
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		PreRunE:      validateGenerationsCommandInput,
+		RunE:         ExecuteGenerationsCleanupCommand,
+	}
+)
+
+func init() {
+	imagesCmd.AddCommand(generationsCmd)
+	defaults := cfg.NewDefaultConfig()
+
+	generationsCmd.PersistentFlags().BoolP("delete", "d", defaults.Delete, "Effectively remove the generations found")
+	addCommonFlagsForImageSelection(generationsCmd, defaults)
+	addCommonFlagsForUsage(generationsCmd, defaults)
+	generationsCmd.PersistentFlags().IntP("keep", "k", defaults.History.Keep,
+		"Keep the most current <k> generations of each tag, including the current one")
+	generationsCmd.PersistentFlags().String("keep-younger-than", defaults.Generations.KeepYoungerThan,
+		"Keep generations that are younger than the duration, regardless of --keep, e.g. [1y2mo3w4d5h6m7s]")
+}
+
+func validateGenerationsCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
+	defer showUsageOnError(cmd, returnErr)
+	if err := validateImageSelection(args); err != nil {
+		return err
+	}
+	if _, err := parseCutOffDateTime(config.Generations.KeepYoungerThan); err != nil {
+		return fmt.Errorf("could not parse keep-younger-than flag: %w", err)
+	}
+	if err := validateUsageConfig(config.Usage); err != nil {
+		return err
+	}
+	if config.Image.All {
+		return nil
+	}
+	namespace, image, err := splitNamespaceAndImagestream(args[0])
+	if err != nil {
+		return fmt.Errorf("could not parse image name: %w", err)
+	}
+	log.WithFields(log.Fields{
+		"namespace": namespace,
+		"image":     image,
+	}).Debug("Using image config")
+	config.Namespace = namespace
+	return nil
+}
+
+// ExecuteGenerationsCleanupCommand executes the generations cleanup command
+func ExecuteGenerationsCleanupCommand(_ *cobra.Command, args []string) error {
+	ctx := context.Background()
+	namespace, imageNames, err := getImageNames(ctx, args)
+	if err != nil {
+		return err
+	}
+	usageNamespaces, err := getUsageNamespaces(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	var results []imageCleanupResult
+	for _, imageName := range imageNames {
+		generations, err := cleanupImageGenerations(ctx, namespace, usageNamespaces, imageName)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to clean up generations of %s/%s", namespace, imageName)
+		}
+		results = append(results, imageCleanupResult{Image: imageName, Tags: generations, Err: err})
+	}
+	return printImageCleanupSummary(results, namespace)
+}
+
+// cleanupImageGenerations removes old generations from all tags of a single image stream and returns the generations
+// found as "tag@digest"
+func cleanupImageGenerations(ctx context.Context, namespace string, usageNamespaces []string, imageName string) ([]string, error) {
+	keepYoungerThan, _ := parseCutOffDateTime(config.Generations.KeepYoungerThan)
+	imageStreamTags, err := openshift.GetImageStreamTags(ctx, namespace, imageName)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve image stream '%s/%s': %w", namespace, imageName, err)
+	}
+
+	var digests []string
+	for _, imageStreamTag := range imageStreamTags {
+		for _, tagEvent := range imageStreamTag.Items {
+			digests = append(digests, tagEvent.Image)
+		}
+	}
+	activeDigests, err := openshift.GetActiveImageDigests(ctx, usageNamespaces, funk.UniqString(digests))
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve active image digests for '%s/%s': %w", namespace, imageName, err)
+	}
+
+	prunable := map[string][]imagev1.TagEvent{}
+	var generations []string
+	for _, imageStreamTag := range imageStreamTags {
+		tagEvents := cleanup.GetPrunableGenerations(imageStreamTag, config.History.Keep, keepYoungerThan, activeDigests)
+		if len(tagEvents) == 0 {
+			continue
+		}
+		prunable[imageStreamTag.Tag] = tagEvents
+		for _, tagEvent := range tagEvents {
+			generations = append(generations, imageStreamTag.Tag+"@"+tagEvent.Image)
+		}
+	}
+
+	if len(generations) == 0 {
+		log.WithFields(log.Fields{
+			"\n - namespace": namespace,
+			"\n - 📺 image":   imageName,
+		}).Info("No old image stream tag generations found")
+		return generations, nil
+	}
+	if config.Delete {
+		log.Infof("Removing %d generations from %s/%s", len(generations), namespace, imageName)
+		if err := openshift.RemoveImageStreamTagGenerations(ctx, namespace, imageName, prunable); err != nil {
+			return nil, fmt.Errorf("could not remove generations from '%s/%s': %w", namespace, imageName, err)
+		}
+	} else {
+		log.Infof("Showing results for --keep=%d and --keep-younger-than=%s", config.History.Keep, config.Generations.KeepYoungerThan)
+		printGenerations(generations, imageName, namespace)
+	}
+	return generations, nil
+}
+
+// printGenerations prints the given generations line by line. In batch mode, only "tag@digest" is printed, otherwise
+// default log with info level
+func printGenerations(generations []string, imageName string, namespace string) {
+	for _, generation := range generations {
+		if config.Log.Batch {
+			fmt.Println(generation)
+		} else {
+			log.Infof("Found generation candidate: %s/%s:%s", namespace, imageName, generation)
+		}
+	}
+}
@@ -2,14 +2,19 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/appuio/seiso/cfg"
 	"github.com/appuio/seiso/pkg/cleanup"
 	"github.com/appuio/seiso/pkg/git"
 	"github.com/appuio/seiso/pkg/openshift"
+	"github.com/appuio/seiso/pkg/registry"
+	imagev1 "github.com/openshift/api/image/v1"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/thoas/go-funk"
 )
 
 var (
@@ -30,22 +35,42 @@ func init() {
 	defaults := cfg.NewDefaultConfig()
 
 	addCommonFlagsForGit(historyCmd, defaults)
+	addCommonFlagsForRegistry(historyCmd, defaults)
+	addCommonFlagsForImageSelection(historyCmd, defaults)
+	addCommonFlagsForTagPatterns(historyCmd, defaults)
+	addCommonFlagsForUsage(historyCmd, defaults)
 	historyCmd.PersistentFlags().IntP("keep", "k", defaults.History.Keep,
 		"Keep most current <k> images. Does not include currently used image tags (if detected).")
-
+	historyCmd.PersistentFlags().Int("keep-minors", defaults.History.KeepMinors,
+		"Keep the newest --keep-patches releases of each of the newest <n> minor versions. Only effective with --tags and --sort=version")
+	historyCmd.PersistentFlags().Int("keep-patches", defaults.History.KeepPatches,
+		"Number of releases to keep per minor version with --keep-minors")
+	historyCmd.PersistentFlags().Bool("keep-latest-per-major", defaults.History.KeepLatestPerMajor,
+		"Keep the latest release of every major version. Only effective with --tags and --sort=version")
+	historyCmd.PersistentFlags().String("drop-prereleases-older-than", defaults.History.DropPrereleasesOlderThan,
+		"Do not keep pre-releases older than the duration by any rule, e.g. [1y2mo3w4d5h6m7s]. Only effective with --tags and --sort=version")
+	historyCmd.PersistentFlags().String("keep-younger-than", defaults.History.KeepYoungerThan,
+		"Keep images that are younger than the duration, regardless of --keep, e.g. [1y2mo3w4d5h6m7s]")
+	historyCmd.PersistentFlags().String("max-age", defaults.History.MaxAge,
+		"Delete inactive images that are older than the duration, regardless of --keep and --keep-younger-than, e.g. [1y2mo3w4d5h6m7s]")
+	historyCmd.PersistentFlags().Bool("no-git", defaults.History.NoGit,
+		"Do not compare with a Git repository, but rank all image tags by the date they were last updated")
 }
 
 func validateHistoryCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
 	defer showUsageOnError(cmd, returnErr)
-	if len(args) == 0 {
-		return missingImageNameError(config.Namespace)
+	if err := validateImageSelection(args); err != nil {
+		return err
+	}
+	if config.Image.All {
+		return validateHistoryFlags()
 	}
 	namespace, image, err := splitNamespaceAndImagestream(args[0])
 	if err != nil {
 		return fmt.Errorf("could not parse image name: %w", err)
 	}
-	if config.Git.Tag && !git.IsValidSortValue(config.Git.SortCriteria) {
-		return fmt.Errorf("invalid sort flag provided: %v", config.Git.SortCriteria)
+	if err := validateHistoryFlags(); err != nil {
+		return err
 	}
 	log.WithFields(log.Fields{
 		"namespace": namespace,
@@ -55,52 +80,233 @@ func validateHistoryCommandInput(cmd *cobra.Command, args []string) (returnErr e
 	return nil
 }
 
+func validateHistoryFlags() error {
+	if config.History.NoGit && config.Git.Tag {
+		return errors.New("--no-git cannot be used with --tags")
+	}
+	if err := validateGitConfig(config.Git); err != nil {
+		return err
+	}
+	if err := validateRetentionFlags(config.History); err != nil {
+		return err
+	}
+	if err := validateAgeFlags(config.History); err != nil {
+		return err
+	}
+	if err := validateTagPatterns(config.Image); err != nil {
+		return err
+	}
+	if err := validateUsageConfig(config.Usage); err != nil {
+		return err
+	}
+	return validateRegistryConfig(config.Registry)
+}
+
+// validateRetentionFlags checks that the semantic version retention rules are only used in version sorted tag mode
+func validateRetentionFlags(c cfg.HistoryConfig) error {
+	if c.KeepMinors == 0 && !c.KeepLatestPerMajor && c.DropPrereleasesOlderThan == "" {
+		return nil
+	}
+	if !isSemverRetentionMode() {
+		return fmt.Errorf("--keep-minors, --keep-latest-per-major and --drop-prereleases-older-than require --tags and --sort=%s", git.SortOptionVersion)
+	}
+	if c.KeepMinors < 0 || c.KeepPatches < 1 {
+		return errors.New("--keep-minors must not be negative and --keep-patches must be at least 1")
+	}
+	if _, err := parseCutOffDateTime(c.DropPrereleasesOlderThan); err != nil {
+		return fmt.Errorf("could not parse drop-prereleases-older-than flag: %w", err)
+	}
+	return nil
+}
+
+// validateAgeFlags checks the durations of --keep-younger-than and --max-age and that they do not contradict each other
+func validateAgeFlags(c cfg.HistoryConfig) error {
+	keepYoungerThan, err := parseCutOffDateTime(c.KeepYoungerThan)
+	if err != nil {
+		return fmt.Errorf("could not parse keep-younger-than flag: %w", err)
+	}
+	maxAge, err := parseCutOffDateTime(c.MaxAge)
+	if err != nil {
+		return fmt.Errorf("could not parse max-age flag: %w", err)
+	}
+	if c.KeepYoungerThan != "" && c.MaxAge != "" && maxAge.After(keepYoungerThan) {
+		return errors.New("--max-age must be longer than --keep-younger-than")
+	}
+	return nil
+}
+
+func isSemverRetentionMode() bool {
+	return config.Git.Tag && git.SortOption(config.Git.SortCriteria) == git.SortOptionVersion
+}
+
 // ExecuteHistoryCleanupCommand executes the history cleanup command
 func ExecuteHistoryCleanupCommand(cmd *cobra.Command, args []string) error {
-	c := config.History
 	ctx := context.Background()
-	namespace, imageName, _ := splitNamespaceAndImagestream(args[0])
-
-	imageStreamObjectTags, err := openshift.GetImageStreamTags(ctx, namespace, imageName)
+	backend, err := registry.NewBackend(&config.Registry)
+	if err != nil {
+		return err
+	}
+	namespace, imageNames, err := getImageNames(ctx, args)
+	if err != nil {
+		return err
+	}
+	var gitCandidates []string
+	if !config.History.NoGit {
+		if err := checkShallowRepository(false); err != nil {
+			return err
+		}
+		gitCandidates, err = git.GetGitCandidateList(&config.Git)
+		if err != nil {
+			return err
+		}
+	}
+	usageNamespaces, err := getUsageNamespaces(ctx, namespace)
 	if err != nil {
-		return fmt.Errorf("could not retrieve image stream '%s/%s': %w", namespace, imageName, err)
+		return err
 	}
 
-	var imageStreamTags []string
-	for _, imageTag := range imageStreamObjectTags {
-		imageStreamTags = append(imageStreamTags, imageTag.Tag)
+	var results []imageCleanupResult
+	for _, imageName := range imageNames {
+		tags, err := cleanupImageHistory(ctx, backend, gitCandidates, namespace, usageNamespaces, imageName)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to clean up history of %s/%s", namespace, imageName)
+		}
+		results = append(results, imageCleanupResult{Image: imageName, Tags: tags, Err: err})
 	}
+	return printImageCleanupSummary(results, namespace)
+}
 
-	matchOption := cleanup.MatchOptionPrefix
-	if config.Git.Tag {
-		matchOption = cleanup.MatchOptionExact
+// cleanupImageHistory runs the history cleanup for a single image and returns the inactive tags found
+func cleanupImageHistory(ctx context.Context, backend registry.Backend, gitCandidates []string, namespace string, usageNamespaces []string, imageName string) ([]string, error) {
+	c := config.History
+	imageStreamObjectTags, err := backend.GetImageTags(ctx, namespace, imageName)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve image stream '%s/%s': %w", namespace, imageName, err)
 	}
 
-	gitCandidates, err := git.GetGitCandidateList(&config.Git)
+	matchingTags, err := getHistoryCandidates(gitCandidates, imageStreamObjectTags, imageName)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var matchingTags = cleanup.GetMatchingTags(&gitCandidates, &imageStreamTags, matchOption)
+	matchingTags = filterTagsByPatterns(matchingTags, imageName)
 
-	activeImageStreamTags, err := openshift.GetActiveImageStreamTags(ctx, namespace, imageName, matchingTags)
+	activeImageStreamTags, err := openshift.GetActiveImageStreamTags(ctx, usageNamespaces, imageName, imageStreamObjectTags, matchingTags)
 	if err != nil {
-		return fmt.Errorf("could not retrieve active image stream tags for '%s/%s': %w", namespace, imageName, err)
+		return nil, fmt.Errorf("could not retrieve active image stream tags for '%s/%s': %w", namespace, imageName, err)
 	}
 
 	inactiveTags := cleanup.GetInactiveImageTags(&activeImageStreamTags, &matchingTags)
-	inactiveTags = cleanup.LimitTags(&inactiveTags, c.Keep)
+	var limitedTags []string
+	if isSemverRetentionMode() {
+		limitedTags = applyRetentionPolicy(inactiveTags, imageStreamObjectTags, imageName)
+	} else {
+		limitedTags = cleanup.LimitTags(&inactiveTags, c.Keep)
+	}
+	inactiveTags = applyAgeRules(inactiveTags, limitedTags, imageStreamObjectTags, imageName)
 	if len(inactiveTags) == 0 {
 		log.WithFields(log.Fields{
 			"\n - namespace": namespace,
 			"\n - 📺 image":   imageName,
 		}).Info("No inactive image stream tags found")
-		return nil
+		return inactiveTags, nil
 	}
 	if config.Delete {
-		DeleteImages(ctx, inactiveTags, imageName, namespace)
+		DeleteImages(ctx, backend, inactiveTags, imageName, namespace)
 	} else {
-		log.Infof("Showing results for --commit-limit=%d and --keep=%d", config.Git.CommitLimit, c.Keep)
+		log.Infof("Showing results for --commit-limit=%d, --keep=%d, --keep-younger-than=%s and --max-age=%s",
+			config.Git.CommitLimit, c.Keep, c.KeepYoungerThan, c.MaxAge)
 		PrintImageTags(inactiveTags, imageName, namespace)
 	}
-	return nil
+	return inactiveTags, nil
+}
+
+// applyRetentionPolicy applies the semantic version retention rules to the inactive tags, logs the rules that kept each
+// tag and returns the remaining tags
+func applyRetentionPolicy(inactiveTags []string, imageStreamObjectTags []imagev1.NamedTagEventList, imageName string) []string {
+	c := config.History
+	policy := cleanup.RetentionPolicy{
+		Keep:               c.Keep,
+		KeepMinors:         c.KeepMinors,
+		KeepPatches:        c.KeepPatches,
+		KeepLatestPerMajor: c.KeepLatestPerMajor,
+	}
+	if c.DropPrereleasesOlderThan != "" {
+		policy.PrereleaseCutOff, _ = parseCutOffDateTime(c.DropPrereleasesOlderThan)
+	}
+	created := make(map[string]time.Time, len(imageStreamObjectTags))
+	for _, imageTag := range imageStreamObjectTags {
+		if len(imageTag.Items) > 0 && !imageTag.Items[0].Created.IsZero() {
+			created[imageTag.Tag] = imageTag.Items[0].Created.Time
+		}
+	}
+
+	toDelete, kept := cleanup.ApplyRetentionPolicy(inactiveTags, created, policy)
+	for _, tag := range inactiveTags {
+		if rules, ok := kept[tag]; ok {
+			log.WithFields(log.Fields{
+				"imageTag": openshift.BuildImageStreamTagName(imageName, tag),
+				"rules":    rules,
+			}).Info("Keeping image tag")
+		}
+	}
+	return toDelete
+}
+
+// getHistoryCandidates returns the image tags matching the git candidates, ordered like the git candidates. With
+// --no-git, all image tags are returned, ordered by the date they were last updated.
+func getHistoryCandidates(gitCandidates []string, imageStreamObjectTags []imagev1.NamedTagEventList, imageName string) ([]string, error) {
+	if config.History.NoGit {
+		return cleanup.SortTagsByCreation(imageStreamObjectTags), nil
+	}
+
+	var imageStreamTags []string
+	for _, imageTag := range imageStreamObjectTags {
+		imageStreamTags = append(imageStreamTags, imageTag.Tag)
+	}
+	matcher, imageStreamTags, err := getTagMatcher(imageStreamTags, imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	matchingTags := cleanup.GetMatchingTags(&gitCandidates, &imageStreamTags, matcher)
+	if !config.Git.Tag {
+		matchingTags = removeAmbiguousTags(gitCandidates, matchingTags, matcher, imageName)
+	}
+	return matchingTags, nil
+}
+
+// applyAgeRules returns the tags to delete out of the inactive tags, based on the tags left after applying --keep.
+// Tags younger than --keep-younger-than are kept, tags older than --max-age are deleted even if kept by --keep. The
+// age of a tag is determined by its newest tag event.
+func applyAgeRules(inactiveTags, limitedTags []string, imageStreamObjectTags []imagev1.NamedTagEventList, imageName string) []string {
+	c := config.History
+	oldTags := inactiveTags
+	if c.KeepYoungerThan != "" {
+		keepYoungerThan, _ := parseCutOffDateTime(c.KeepYoungerThan)
+		oldTags = cleanup.FilterImageTagsByTime(&imageStreamObjectTags, keepYoungerThan)
+	}
+	var expiredTags []string
+	if c.MaxAge != "" {
+		maxAge, _ := parseCutOffDateTime(c.MaxAge)
+		expiredTags = cleanup.FilterImageTagsByTime(&imageStreamObjectTags, maxAge)
+	}
+
+	var tags []string
+	for _, tag := range inactiveTags {
+		imageTag := openshift.BuildImageStreamTagName(imageName, tag)
+		switch {
+		case funk.ContainsString(expiredTags, tag):
+			if !funk.ContainsString(limitedTags, tag) {
+				log.WithField("imageTag", imageTag).Info("Image tag is older than --max-age")
+			}
+			tags = append(tags, tag)
+		case !funk.ContainsString(limitedTags, tag):
+			continue
+		case !funk.ContainsString(oldTags, tag):
+			log.WithField("imageTag", imageTag).Info("Keeping image tag younger than --keep-younger-than")
+		default:
+			tags = append(tags, tag)
+		}
+	}
+	return tags
 }
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appuio/seiso/cfg"
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_validateRetentionFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		git     cfg.GitConfig
+		history cfg.HistoryConfig
+		wantErr bool
+	}{
+		{
+			name:    "ShouldAccept_DefaultConfig",
+			git:     cfg.NewDefaultConfig().Git,
+			history: cfg.NewDefaultConfig().History,
+		},
+		{
+			name:    "ShouldAccept_RulesInVersionTagMode",
+			git:     cfg.GitConfig{Tag: true, SortCriteria: "version"},
+			history: cfg.HistoryConfig{KeepMinors: 3, KeepPatches: 2, KeepLatestPerMajor: true, DropPrereleasesOlderThan: "2w"},
+		},
+		{
+			name:    "ShouldThrowError_IfNotInTagMode",
+			git:     cfg.GitConfig{SortCriteria: "version"},
+			history: cfg.HistoryConfig{KeepLatestPerMajor: true, KeepPatches: 1},
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfSortedAlphabetically",
+			git:     cfg.GitConfig{Tag: true, SortCriteria: "alphabetic"},
+			history: cfg.HistoryConfig{KeepMinors: 1, KeepPatches: 1},
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfNoPatchesKept",
+			git:     cfg.GitConfig{Tag: true, SortCriteria: "version"},
+			history: cfg.HistoryConfig{KeepMinors: 1, KeepPatches: 0},
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfDurationInvalid",
+			git:     cfg.GitConfig{Tag: true, SortCriteria: "version"},
+			history: cfg.HistoryConfig{KeepPatches: 1, DropPrereleasesOlderThan: "invalid"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config = cfg.NewDefaultConfig()
+			config.Git = tt.git
+
+			err := validateRetentionFlags(tt.history)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_validateAgeFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		history cfg.HistoryConfig
+		wantErr bool
+	}{
+		{
+			name:    "ShouldAccept_DefaultConfig",
+			history: cfg.NewDefaultConfig().History,
+		},
+		{
+			name:    "ShouldAccept_MaxAgeLongerThanKeepYoungerThan",
+			history: cfg.HistoryConfig{KeepYoungerThan: "2w", MaxAge: "1y"},
+		},
+		{
+			name:    "ShouldThrowError_IfMaxAgeShorterThanKeepYoungerThan",
+			history: cfg.HistoryConfig{KeepYoungerThan: "2w", MaxAge: "1w"},
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfKeepYoungerThanInvalid",
+			history: cfg.HistoryConfig{KeepYoungerThan: "invalid"},
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfMaxAgeInvalid",
+			history: cfg.HistoryConfig{MaxAge: "invalid"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAgeFlags(tt.history)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_applyAgeRules(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	imageStreamObjectTags := []imagev1.NamedTagEventList{
+		{Tag: "a", Items: []imagev1.TagEvent{{Created: metav1.NewTime(now.Add(-1 * day))}}},
+		{Tag: "b", Items: []imagev1.TagEvent{{Created: metav1.NewTime(now.Add(-10 * day))}}},
+		{Tag: "c", Items: []imagev1.TagEvent{{Created: metav1.NewTime(now.Add(-100 * day))}}},
+		{Tag: "d", Items: []imagev1.TagEvent{{Created: metav1.NewTime(now.Add(-400 * day))}}},
+	}
+	inactiveTags := []string{"a", "b", "c", "d"}
+	tests := []struct {
+		name        string
+		history     cfg.HistoryConfig
+		limitedTags []string
+		want        []string
+	}{
+		{
+			name:        "ShouldReturnLimitedTags_IfNoAgeRules",
+			limitedTags: []string{"b", "c", "d"},
+			want:        []string{"b", "c", "d"},
+		},
+		{
+			name:        "ShouldKeepYoungTags_IfKeepYoungerThan",
+			history:     cfg.HistoryConfig{KeepYoungerThan: "2w"},
+			limitedTags: []string{"a", "b", "c", "d"},
+			want:        []string{"c", "d"},
+		},
+		{
+			name:        "ShouldDeleteOldTags_IfMaxAge",
+			history:     cfg.HistoryConfig{MaxAge: "1y"},
+			limitedTags: []string{},
+			want:        []string{"d"},
+		},
+		{
+			name:        "ShouldCombineAgeRules",
+			history:     cfg.HistoryConfig{KeepYoungerThan: "2w", MaxAge: "3mo"},
+			limitedTags: []string{"b"},
+			want:        []string{"c", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config = cfg.NewDefaultConfig()
+			config.History = tt.history
+
+			result := applyAgeRules(inactiveTags, tt.limitedTags, imageStreamObjectTags, "image")
+
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+func Test_getHistoryCandidates_NoGit(t *testing.T) {
+	config = cfg.NewDefaultConfig()
+	config.History.NoGit = true
+	now := time.Now()
+	imageStreamObjectTags := []imagev1.NamedTagEventList{
+		{Tag: "a", Items: []imagev1.TagEvent{{Created: metav1.NewTime(now.Add(-2 * time.Hour))}}},
+		{Tag: "b", Items: []imagev1.TagEvent{{Created: metav1.NewTime(now)}}},
+	}
+
+	result, err := getHistoryCandidates(nil, imageStreamObjectTags, "image")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "a"}, result)
+}
+
+func Test_validateHistoryFlags_NoGit(t *testing.T) {
+	config = cfg.NewDefaultConfig()
+	config.History.NoGit = true
+	config.Git.Tag = true
+
+	assert.Error(t, validateHistoryFlags())
+}
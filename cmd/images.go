@@ -1,9 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"errors"
-	"github.com/spf13/cobra"
+	"fmt"
 	"strings"
+
+	"github.com/appuio/seiso/pkg/openshift"
+	"github.com/appuio/seiso/pkg/registry"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type (
+	// imageCleanupResult holds the outcome of a cleanup run for a single image
+	imageCleanupResult struct {
+		Image string
+		Tags  []string
+		Err   error
+	}
 )
 
 // imagesCmd represents the images command
@@ -39,3 +54,83 @@ func splitNamespaceAndImagestream(repo string) (namespace string, image string,
 	}
 	return namespace, image, nil
 }
+
+// validateImageSelection checks that either a single image is given or --all is used
+func validateImageSelection(args []string) error {
+	if !config.Image.All {
+		if len(args) == 0 {
+			return missingImageNameError(config.Namespace)
+		}
+		if len(config.Resource.Labels) > 0 {
+			return errors.New("--label can only be used together with --all")
+		}
+		return nil
+	}
+	if len(args) > 0 {
+		return errors.New("--all cannot be combined with an image name, use --namespace to select the namespace")
+	}
+	if registry.BackendOption(config.Registry.Backend) != registry.BackendOptionOpenShift {
+		return fmt.Errorf("--all is only supported with registry backend %s", registry.BackendOptionOpenShift)
+	}
+	for _, label := range config.Resource.Labels {
+		if !strings.Contains(label, "=") {
+			return fmt.Errorf("incorrect label format does not match expected \"key=value\" format: %s", label)
+		}
+	}
+	return nil
+}
+
+// getImageNames returns the namespace and the names of the images to clean up. With --all, these are all image
+// streams in the namespace matching the labels, otherwise the single image given as argument.
+func getImageNames(ctx context.Context, args []string) (string, []string, error) {
+	if !config.Image.All {
+		namespace, imageName, err := splitNamespaceAndImagestream(args[0])
+		return namespace, []string{imageName}, err
+	}
+	namespace := config.Namespace
+	imageStreams, err := openshift.ListImageStreams(ctx, namespace, toListOptions(config.Resource.Labels))
+	if err != nil {
+		return "", nil, fmt.Errorf("could not list image streams in '%s': %w", namespace, err)
+	}
+	var imageNames []string
+	for _, imageStream := range imageStreams {
+		imageNames = append(imageNames, imageStream.Name)
+	}
+	log.WithFields(log.Fields{
+		"namespace": namespace,
+		"images":    imageNames,
+	}).Debug("Found image streams")
+	return namespace, imageNames, nil
+}
+
+// printImageCleanupSummary prints one line per image with the number of tags found and returns an error if the
+// cleanup of any image failed. It only prints with --all, even if the namespace contains a single image.
+func printImageCleanupSummary(results []imageCleanupResult, namespace string) error {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if config.Image.All {
+		for _, result := range results {
+			fields := log.Fields{
+				"\n - namespace": namespace,
+				"\n - 📺 image":   result.Image,
+				"\n - tags":      len(result.Tags),
+			}
+			if result.Err != nil {
+				log.WithFields(fields).WithError(result.Err).Error("Summary")
+			} else {
+				log.WithFields(fields).Info("Summary")
+			}
+		}
+	}
+	if failed == 1 && !config.Image.All {
+		return results[0].Err
+	}
+	if failed > 0 {
+		return fmt.Errorf("cleanup failed for %d of %d images in '%s'", failed, len(results), namespace)
+	}
+	return nil
+}
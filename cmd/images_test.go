@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/appuio/seiso/cfg"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -75,3 +77,115 @@ func Test_SplitNamespaceAndImagestream(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateImageSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  cfg.Configuration
+		wantErr bool
+	}{
+		{
+			name: "ShouldAccept_SingleImage",
+			args: []string{"namespace/image"},
+		},
+		{
+			name:    "ShouldThrowError_IfNoImageAndNotAll",
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfLabelsWithoutAll",
+			args: []string{"namespace/image"},
+			config: cfg.Configuration{
+				Resource: cfg.ResourceConfig{Labels: []string{"app=example"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldAccept_AllWithLabels",
+			config: cfg.Configuration{
+				Image:    cfg.ImageConfig{All: true},
+				Registry: cfg.RegistryConfig{Backend: "openshift"},
+				Resource: cfg.ResourceConfig{Labels: []string{"app=example"}},
+			},
+		},
+		{
+			name: "ShouldThrowError_IfAllWithImage",
+			args: []string{"namespace/image"},
+			config: cfg.Configuration{
+				Image:    cfg.ImageConfig{All: true},
+				Registry: cfg.RegistryConfig{Backend: "openshift"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfAllWithOtherBackend",
+			config: cfg.Configuration{
+				Image:    cfg.ImageConfig{All: true},
+				Registry: cfg.RegistryConfig{Backend: "docker-v2"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfInvalidLabel",
+			config: cfg.Configuration{
+				Image:    cfg.ImageConfig{All: true},
+				Registry: cfg.RegistryConfig{Backend: "openshift"},
+				Resource: cfg.ResourceConfig{Labels: []string{"app"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config = &tt.config
+			err := validateImageSelection(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_printImageCleanupSummary(t *testing.T) {
+	imageErr := errors.New("image stream not found")
+	tests := []struct {
+		name    string
+		all     bool
+		results []imageCleanupResult
+		wantErr string
+	}{
+		{
+			name:    "ShouldReturnImageError_IfSingleImage",
+			results: []imageCleanupResult{{Image: "app", Err: imageErr}},
+			wantErr: imageErr.Error(),
+		},
+		{
+			name:    "ShouldReturnSummaryError_IfAllWithSingleImage",
+			all:     true,
+			results: []imageCleanupResult{{Image: "app", Err: imageErr}},
+			wantErr: "cleanup failed for 1 of 1 images in 'namespace'",
+		},
+		{
+			name:    "ShouldSucceed_IfAllImagesSucceed",
+			all:     true,
+			results: []imageCleanupResult{{Image: "app", Tags: []string{"v1"}}, {Image: "web"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config = cfg.NewDefaultConfig()
+			config.Image.All = tt.all
+
+			err := printImageCleanupSummary(tt.results, "namespace")
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
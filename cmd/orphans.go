@@ -9,7 +9,7 @@ import (
 	"github.com/appuio/seiso/cfg"
 	"github.com/appuio/seiso/pkg/cleanup"
 	"github.com/appuio/seiso/pkg/git"
-	"github.com/appuio/seiso/pkg/openshift"
+	"github.com/appuio/seiso/pkg/registry"
 	"github.com/appuio/seiso/pkg/util"
 	"github.com/karrick/tparse/v2"
 	log "github.com/sirupsen/logrus"
@@ -43,6 +43,10 @@ func init() {
 	defaults := cfg.NewDefaultConfig()
 
 	addCommonFlagsForGit(orphanCmd, defaults)
+	addCommonFlagsForRegistry(orphanCmd, defaults)
+	addCommonFlagsForImageSelection(orphanCmd, defaults)
+	addCommonFlagsForTagPatterns(orphanCmd, defaults)
+	addCommonFlagsForUsage(orphanCmd, defaults)
 	orphanCmd.PersistentFlags().String(orphanOlderThanCliFlag, defaults.Orphan.OlderThan,
 		"Delete images that are older than the duration. Ex.: [1y2mo3w4d5h6m7s]")
 	orphanCmd.PersistentFlags().StringP(orphanDeletionPatternCliFlag, "r", defaults.Orphan.OrphanDeletionRegex,
@@ -51,14 +55,29 @@ func init() {
 
 func validateOrphanCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
 	defer showUsageOnError(cmd, returnErr)
-	if len(args) == 0 {
-		return missingImageNameError(config.Namespace)
+	if err := validateImageSelection(args); err != nil {
+		return err
+	}
+	if config.Image.All {
+		return validateOrphanFlags()
 	}
-	c := config.Orphan
 	namespace, image, err := splitNamespaceAndImagestream(args[0])
 	if err != nil {
 		return fmt.Errorf("could not parse image name: %w", err)
 	}
+	if err := validateOrphanFlags(); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"namespace": namespace,
+		"image":     image,
+	}).Debug("Using image config")
+	config.Namespace = namespace
+	return nil
+}
+
+func validateOrphanFlags() error {
+	c := config.Orphan
 	if _, err := parseOrphanDeletionRegex(c.OrphanDeletionRegex); err != nil {
 		return fmt.Errorf("could not parse orphan deletion pattern: %w", err)
 	}
@@ -67,63 +86,91 @@ func validateOrphanCommandInput(cmd *cobra.Command, args []string) (returnErr er
 		return fmt.Errorf("could not parse older-than flag: %w", err)
 	}
 
-	if config.Git.Tag && !git.IsValidSortValue(config.Git.SortCriteria) {
-		return fmt.Errorf("invalid sort flag provided: %v", config.Git.SortCriteria)
+	if err := validateGitConfig(config.Git); err != nil {
+		return err
 	}
-	log.WithFields(log.Fields{
-		"namespace": namespace,
-		"image":     image,
-	}).Debug("Using image config")
-	config.Namespace = namespace
-	return nil
+	if err := validateTagPatterns(config.Image); err != nil {
+		return err
+	}
+	if err := validateUsageConfig(config.Usage); err != nil {
+		return err
+	}
+	return validateRegistryConfig(config.Registry)
 }
 
 // ExecuteOrphanCleanupCommand executes the orphan cleanup command
 func ExecuteOrphanCleanupCommand(_ *cobra.Command, args []string) error {
-	c := config.Orphan
 	ctx := context.Background()
-	namespace, imageName, _ := splitNamespaceAndImagestream(args[0])
+	backend, err := registry.NewBackend(&config.Registry)
+	if err != nil {
+		return err
+	}
+	namespace, imageNames, err := getImageNames(ctx, args)
+	if err != nil {
+		return err
+	}
+	if err := checkShallowRepository(config.Delete); err != nil {
+		return err
+	}
+	gitCandidates, err := git.GetGitCandidateList(&config.Git)
+	if err != nil {
+		return err
+	}
+	usageNamespaces, err := getUsageNamespaces(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	var results []imageCleanupResult
+	for _, imageName := range imageNames {
+		tags, err := cleanupImageOrphans(ctx, backend, gitCandidates, namespace, usageNamespaces, imageName)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to clean up orphans of %s/%s", namespace, imageName)
+		}
+		results = append(results, imageCleanupResult{Image: imageName, Tags: tags, Err: err})
+	}
+	return printImageCleanupSummary(results, namespace)
+}
 
-	allImageTags, err := openshift.GetImageStreamTags(ctx, namespace, imageName)
+// cleanupImageOrphans runs the orphan cleanup for a single image and returns the orphaned tags found
+func cleanupImageOrphans(ctx context.Context, backend registry.Backend, gitCandidates []string, namespace string, usageNamespaces []string, imageName string) ([]string, error) {
+	c := config.Orphan
+	allImageTags, err := backend.GetImageTags(ctx, namespace, imageName)
 	if err != nil {
-		return fmt.Errorf("could not retrieve image stream '%v/%v': %w", namespace, imageName, err)
+		return nil, fmt.Errorf("could not retrieve image stream '%v/%v': %w", namespace, imageName, err)
 	}
 
 	cutOffDateTime, _ := parseCutOffDateTime(c.OlderThan)
 	orphanIncludeRegex, _ := parseOrphanDeletionRegex(c.OrphanDeletionRegex)
 
-	matchOption := cleanup.MatchOptionPrefix
-	if config.Git.Tag {
-		matchOption = cleanup.MatchOptionExact
-	}
-
-	gitCandidates, err := git.GetGitCandidateList(&config.Git)
+	imageTagList := cleanup.FilterImageTagsByTime(&allImageTags, cutOffDateTime)
+	matcher, imageTagList, err := getTagMatcher(imageTagList, imageName)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	imageTagList := cleanup.FilterImageTagsByTime(&allImageTags, cutOffDateTime)
-	imageTagList = cleanup.FilterOrphanImageTags(&gitCandidates, &imageTagList, matchOption)
+	imageTagList = cleanup.FilterOrphanImageTags(&gitCandidates, &imageTagList, matcher)
 	imageTagList = cleanup.FilterByRegex(&imageTagList, orphanIncludeRegex)
-	imageTagList, err = cleanup.FilterActiveImageTags(ctx, namespace, imageName, imageTagList, &imageTagList)
+	imageTagList = filterTagsByPatterns(imageTagList, imageName)
+	imageTagList, err = cleanup.FilterActiveImageTags(ctx, usageNamespaces, imageName, allImageTags, &imageTagList)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(imageTagList) == 0 {
 		log.WithFields(log.Fields{
 			"\n - namespace": namespace,
 			"\n - 📺 image":   imageName,
 		}).Info("No orphaned image stream tags found")
-		return nil
+		return imageTagList, nil
 	}
 
 	if config.Delete {
-		DeleteImages(ctx, imageTagList, imageName, namespace)
+		DeleteImages(ctx, backend, imageTagList, imageName, namespace)
 	} else {
 		log.Infof("Showing results for --commit-limit=%d and --older-than=%s", config.Git.CommitLimit, c.OlderThan)
 		PrintImageTags(imageTagList, imageName, namespace)
 	}
 
-	return nil
+	return imageTagList, nil
 }
 
 func parseOrphanDeletionRegex(orphanIncludeRegex string) (*regexp.Regexp, error) {
@@ -100,6 +100,18 @@ func Test_validateOrphanCommandInput(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "ShouldThrowError_IfRegistryUrlMissing",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Registry: cfg.RegistryConfig{
+						Backend: "docker-v2",
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
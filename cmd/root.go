@@ -8,7 +8,9 @@ import (
 
 	"github.com/appuio/seiso/cfg"
 	"github.com/knadh/koanf"
+	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/posflag"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -33,6 +35,7 @@ func Execute() error {
 }
 
 func init() {
+	rootCmd.PersistentFlags().String("config", "", "Path to a YAML file with configuration values, also given with SEISO_CONFIG")
 	rootCmd.PersistentFlags().StringP("namespace", "n", config.Namespace, "Cluster namespace of current context")
 	rootCmd.PersistentFlags().String("log.level", config.Log.LogLevel, "Log level, one of [debug info warn error fatal]")
 	rootCmd.PersistentFlags().BoolP("log.verbose", "v", config.Log.Verbose, "Shorthand for \"--log.level debug\"")
@@ -45,11 +48,15 @@ func initRootConfig() {
 	bindFlags(rootCmd.Flags())
 }
 
-// parseConfig reads the flags and ENV vars
+// parseConfig reads the config file, the ENV vars and the flags, the latter taking precedence
 func parseConfig(cmd *cobra.Command, args []string) error {
 
+	if err := loadConfigFile(cmd); err != nil {
+		return err
+	}
 	loadEnvironmentVariables()
-	bindFlags(cmd.PersistentFlags())
+	// the inherited flags of the root command are bound again so that they take precedence over the config file
+	bindFlags(cmd.Flags())
 
 	if err := koanfInstance.Unmarshal("", &config); err != nil {
 		return fmt.Errorf("could not read config: %w", err)
@@ -83,17 +90,48 @@ func parseConfig(cmd *cobra.Command, args []string) error {
 		config.Namespace = namespace
 	}
 	log.Infof("Seiso %s", version)
+	registryConfig := config.Registry
+	if registryConfig.Token != "" {
+		registryConfig.Token = "***"
+	}
+	gitConfig := config.Git
+	if gitConfig.RepoPassword != "" {
+		gitConfig.RepoPassword = "***"
+	}
+	if gitConfig.RepoSSHKeyPassword != "" {
+		gitConfig.RepoSSHKeyPassword = "***"
+	}
 	log.WithFields(log.Fields{
-		"namespace": config.Namespace,
-		"git":       config.Git,
-		"log":       config.Log,
-		"history":   config.History,
-		"orphan":    config.Orphan,
-		"resource":  config.Resource,
+		"namespace":   config.Namespace,
+		"git":         gitConfig,
+		"log":         config.Log,
+		"history":     config.History,
+		"orphan":      config.Orphan,
+		"generations": config.Generations,
+		"resource":    config.Resource,
+		"registry":    registryConfig,
+		"image":       config.Image,
+		"usage":       config.Usage,
 	}).Debug("Using config")
 	return nil
 }
 
+// loadConfigFile reads the YAML file given with --config or SEISO_CONFIG, if any. The keys are the same as the flag
+// names, e.g. "usage-resources-add".
+func loadConfigFile(cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		path = os.Getenv("SEISO_CONFIG")
+	}
+	if path == "" {
+		return nil
+	}
+	if err := koanfInstance.Load(file.Provider(path), yaml.Parser()); err != nil {
+		return fmt.Errorf("could not read config file '%s': %w", path, err)
+	}
+	return nil
+}
+
 func loadEnvironmentVariables() {
 	prefix := "SEISO_"
 	err := koanfInstance.Load(env.Provider(prefix, ".", func(s string) string {
@@ -113,7 +151,14 @@ func loadEnvironmentVariables() {
 }
 
 func bindFlags(flagSet *pflag.FlagSet) {
-	err := koanfInstance.Load(posflag.Provider(flagSet, ".", koanfInstance), nil)
+	err := koanfInstance.Load(posflag.ProviderWithFlag(flagSet, ".", koanfInstance, func(f *pflag.Flag) (string, interface{}) {
+		// string arrays are not split at commas, which is needed for flags taking regular expressions
+		if f.Value.Type() == "stringArray" {
+			values, _ := flagSet.GetStringArray(f.Name)
+			return f.Name, values
+		}
+		return f.Name, posflag.FlagVal(flagSet, f)
+	}), nil)
 	if err != nil {
 		log.WithError(err).Fatal("Could not bind flags")
 	}
@@ -40,6 +40,7 @@ func init() {
 		"Keep most current <k> Secrets; does not include currently used secret (if detected)")
 	secretCmd.PersistentFlags().String("older-than", defaults.Resource.OlderThan,
 		"Delete Secrets that are older than the duration, e.g. [1y2mo3w4d5h6m7s]")
+	addCommonFlagsForUsageResources(secretCmd, defaults)
 }
 
 func validateSecretCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
@@ -55,7 +56,7 @@ func validateSecretCommandInput(cmd *cobra.Command, args []string) (returnErr er
 	if _, err := parseCutOffDateTime(config.Resource.OlderThan); err != nil {
 		return fmt.Errorf("could not parse older-than flag: %w", err)
 	}
-	return nil
+	return applyUsageConfig(config.Usage)
 }
 
 func executeSecretCleanupCommand(cmd *cobra.Command, args []string) error {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,16 +20,52 @@ type MatchOption string
 const (
 	// MatchOptionExact for exact matches
 	MatchOptionExact MatchOption = "exact"
-	// MatchOptionPrefix for prefix matches
+	// MatchOptionPrefix for prefix matches: the image tag is a (short) prefix of the commit SHA, or the full commit
+	// SHA followed by a dash and a suffix (e.g. "<sha>-debug")
 	MatchOptionPrefix MatchOption = "prefix"
 )
 
-// GetMatchingTags returns all image tags matching one of the provided git tags
-func GetMatchingTags(gitTags, imageTags *[]string, matchOption MatchOption) []string {
+// Matcher decides whether an image tag matches a git value (a commit SHA or a git tag)
+type Matcher interface {
+	Match(imageTag, gitValue string) bool
+}
+
+// Match matches the image tag with the git value according to the match option
+func (o MatchOption) Match(imageTag, gitValue string) bool {
+	return match(imageTag, gitValue, o)
+}
+
+// TagCommitMatcher matches image tags with git tags by name, or by the commits the git tags point to, so that an
+// image tagged with the (short) SHA of a tagged commit matches the git tag
+type TagCommitMatcher struct {
+	// Commits are the commits of the git tags, indexed by git tag
+	Commits map[string][]string
+	// MinLength is the minimum length of an image tag to match as commit SHA
+	MinLength int
+}
+
+// Match returns true if the image tag equals the git tag or is a prefix of a commit the git tag points to
+func (m *TagCommitMatcher) Match(imageTag, gitTag string) bool {
+	if imageTag == gitTag {
+		return true
+	}
+	if len(imageTag) < m.MinLength {
+		return false
+	}
+	for _, commit := range m.Commits[gitTag] {
+		if strings.HasPrefix(commit, imageTag) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMatchingTags returns all image tags matching one of the provided git tags, ordered like the git tags
+func GetMatchingTags(gitTags, imageTags *[]string, matcher Matcher) []string {
 	var matchingTags []string
 
 	log.WithFields(log.Fields{
-		"match":     matchOption,
+		"match":     matcher,
 		"imageTags": imageTags,
 		"gitTags":   gitTags,
 	}).Debug("Matching imageTags with gitTags")
@@ -39,7 +76,7 @@ func GetMatchingTags(gitTags, imageTags *[]string, matchOption MatchOption) []st
 
 	for _, gitTag := range *gitTags {
 		for _, imageTag := range *imageTags {
-			if match(imageTag, gitTag, matchOption) {
+			if matcher.Match(imageTag, gitTag) && !funk.ContainsString(matchingTags, imageTag) {
 				matchingTags = append(matchingTags, imageTag)
 				log.WithFields(log.Fields{
 					"gitTag":   gitTag,
@@ -61,7 +98,7 @@ func GetInactiveImageTags(activeTags, allImageTags *[]string) []string {
 }
 
 // FilterOrphanImageTags returns the tags that do not have any git commit match
-func FilterOrphanImageTags(gitValues, imageTags *[]string, matchOption MatchOption) []string {
+func FilterOrphanImageTags(gitValues, imageTags *[]string, matcher Matcher) []string {
 
 	log.WithFields(log.Fields{
 		"imageTagsToFilter": imageTags,
@@ -70,7 +107,7 @@ func FilterOrphanImageTags(gitValues, imageTags *[]string, matchOption MatchOpti
 
 	orphans := funk.FilterString(*imageTags, func(imageTag string) bool {
 		for _, gitValue := range *gitValues {
-			if match(imageTag, gitValue, matchOption) {
+			if matcher.Match(imageTag, gitValue) {
 				return false
 			}
 		}
@@ -98,6 +135,59 @@ func FilterByRegex(imageTags *[]string, regexp *regexp.Regexp) []string {
 	return matchedTags
 }
 
+// SplitByPatterns splits the tags into the tags matching any of the patterns and the others
+func SplitByPatterns(imageTags []string, patterns []*regexp.Regexp) (matchingTags []string, otherTags []string) {
+	for _, tag := range imageTags {
+		matched := false
+		for _, pattern := range patterns {
+			if pattern.MatchString(tag) {
+				log.WithFields(log.Fields{
+					"imageTag": tag,
+					"pattern":  pattern,
+				}).Debug("Image tag matches pattern")
+				matched = true
+				break
+			}
+		}
+		if matched {
+			matchingTags = append(matchingTags, tag)
+		} else {
+			otherTags = append(otherTags, tag)
+		}
+	}
+	return matchingTags, otherTags
+}
+
+// SplitShortTags splits the tags into the tags with at least minLength characters and the shorter tags
+func SplitShortTags(imageTags []string, minLength int) (tags []string, shortTags []string) {
+	for _, tag := range imageTags {
+		if len(tag) < minLength {
+			shortTags = append(shortTags, tag)
+		} else {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, shortTags
+}
+
+// FindAmbiguousTags returns the image tags matching more than one of the git values, e.g. short SHAs that are the
+// prefix of several commits
+func FindAmbiguousTags(gitValues, imageTags []string, matcher Matcher) []string {
+	var ambiguousTags []string
+	for _, imageTag := range imageTags {
+		matches := 0
+		for _, gitValue := range gitValues {
+			if matcher.Match(imageTag, gitValue) {
+				matches++
+			}
+		}
+		if matches > 1 {
+			ambiguousTags = append(ambiguousTags, imageTag)
+		}
+	}
+	return ambiguousTags
+}
+
 // LimitTags returns the tags which should not be kept by removing the first n tags
 func LimitTags(tags *[]string, keep int) []string {
 	if len(*tags) > keep {
@@ -109,19 +199,14 @@ func LimitTags(tags *[]string, keep int) []string {
 	return []string{}
 }
 
-// FilterImageTagsByTime returns the tags which are older than the specified time
+// FilterImageTagsByTime returns the tags which are older than the specified time. Tags without a known creation date
+// are never returned.
 func FilterImageTagsByTime(imageStreamObjectTags *[]imagev1.NamedTagEventList, olderThan time.Time) []string {
 	var imageStreamTags []string
 
 	for _, imageStreamTag := range *imageStreamObjectTags {
-		lastUpdatedDate := imageStreamTag.Items[0].Created.Time
-		for _, tagEvent := range imageStreamTag.Items {
-			if lastUpdatedDate.Before(tagEvent.Created.Time) {
-				lastUpdatedDate = tagEvent.Created.Time
-			}
-		}
-
-		if lastUpdatedDate.Before(olderThan) {
+		updated := lastUpdated(imageStreamTag)
+		if !updated.IsZero() && updated.Before(olderThan) {
 			imageStreamTags = append(imageStreamTags, imageStreamTag.Tag)
 		}
 	}
@@ -129,21 +214,73 @@ func FilterImageTagsByTime(imageStreamObjectTags *[]imagev1.NamedTagEventList, o
 	return imageStreamTags
 }
 
+// SortTagsByCreation returns the tags sorted by their newest tag event, newest first
+func SortTagsByCreation(imageStreamObjectTags []imagev1.NamedTagEventList) []string {
+	sortedTags := make([]imagev1.NamedTagEventList, len(imageStreamObjectTags))
+	copy(sortedTags, imageStreamObjectTags)
+	sort.SliceStable(sortedTags, func(i, j int) bool {
+		return lastUpdated(sortedTags[i]).After(lastUpdated(sortedTags[j]))
+	})
+
+	tags := make([]string, len(sortedTags))
+	for i, imageStreamTag := range sortedTags {
+		tags[i] = imageStreamTag.Tag
+	}
+	return tags
+}
+
+// lastUpdated returns the creation time of the newest tag event
+func lastUpdated(imageStreamTag imagev1.NamedTagEventList) time.Time {
+	var lastUpdatedDate time.Time
+	for _, tagEvent := range imageStreamTag.Items {
+		if lastUpdatedDate.Before(tagEvent.Created.Time) {
+			lastUpdatedDate = tagEvent.Created.Time
+		}
+	}
+	return lastUpdatedDate
+}
+
+// GetPrunableGenerations returns the generations of an image stream tag that can be removed from its history. The
+// current generation, the newest <keep> generations, generations created after keepYoungerThan and generations whose
+// digest is in activeDigests are kept.
+func GetPrunableGenerations(imageStreamTag imagev1.NamedTagEventList, keep int, keepYoungerThan time.Time, activeDigests []string) []imagev1.TagEvent {
+	var prunable []imagev1.TagEvent
+	for i, tagEvent := range imageStreamTag.Items {
+		if i == 0 || i < keep {
+			continue
+		}
+		if tagEvent.Created.Time.After(keepYoungerThan) {
+			continue
+		}
+		if funk.ContainsString(activeDigests, tagEvent.Image) {
+			log.WithFields(log.Fields{
+				"tag":    imageStreamTag.Tag,
+				"digest": tagEvent.Image,
+			}).Debug("Keeping generation in use")
+			continue
+		}
+		prunable = append(prunable, tagEvent)
+	}
+	return prunable
+}
+
 func match(imageTag, value string, matchOption MatchOption) bool {
 	switch matchOption {
 	case MatchOptionPrefix:
-		return strings.HasPrefix(imageTag, value)
+		return strings.HasPrefix(value, imageTag) || strings.HasPrefix(imageTag, value+"-")
 	case MatchOptionExact:
 		return imageTag == value
 	}
 	return false
 }
 
-// FilterActiveImageTags first gets all actively used image tags from imageStreamTags, then filters them out from matchingTags
-func FilterActiveImageTags(ctx context.Context, namespace string, imageName string, imageStreamTags []string, matchingTags *[]string) ([]string, error) {
-	activeImageStreamTags, err := openshift.GetActiveImageStreamTags(ctx, namespace, imageName, imageStreamTags)
+// FilterActiveImageTags first gets all image tags from matchingTags actively used in any of the namespaces, then
+// filters them out. Tags sharing their digest with another tag in allImageStreamTags are active if any of these tags
+// is used.
+func FilterActiveImageTags(ctx context.Context, namespaces []string, imageName string, allImageStreamTags []imagev1.NamedTagEventList, matchingTags *[]string) ([]string, error) {
+	activeImageStreamTags, err := openshift.GetActiveImageStreamTags(ctx, namespaces, imageName, allImageStreamTags, *matchingTags)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve active image tags from %v/%v': %w", namespace, imageName, err)
+		return nil, fmt.Errorf("could not retrieve active image tags of '%v' from %v: %w", imageName, namespaces, err)
 	}
 
 	log.WithField("activeTags", activeImageStreamTags).Debug("Found currently active image tags")
@@ -58,8 +58,8 @@ func Test_GetMatchingTags(t *testing.T) {
 				"4b35e092ad45a626d9a43b7bc7b03e7f7c3c8037",
 				"c8a693ad89e7069674eda512c553ff56d3ca2ffd-debug",
 			},
+			// the image tag must be a prefix of the commit SHA, not the other way round
 			expected: []string{
-				"0b81a958f590ed7ed8be6ec0a2a87816228a482c",
 				"108f2be974f8e1e5fec8bc759ecf824e81565747",
 				"4cb7de27c985216b8888ff6049294dae02f3282e",
 				"c8a693ad89e7069674eda512c553ff56d3ca2ffd",
@@ -225,6 +225,16 @@ func Test_FilterByRegex(t *testing.T) {
 	}
 }
 
+func TestSplitByPatterns(t *testing.T) {
+	patterns := []*regexp.Regexp{regexp.MustCompile("^(latest|stable)$"), regexp.MustCompile("^(prod|release)-")}
+	tags := []string{"latest", "a3d0df2", "prod-1", "latest-debug", "release-2021", "stable"}
+
+	matching, others := SplitByPatterns(tags, patterns)
+
+	assert.Equal(t, []string{"latest", "prod-1", "release-2021", "stable"}, matching)
+	assert.Equal(t, []string{"a3d0df2", "latest-debug"}, others)
+}
+
 func Test_LimitTags(t *testing.T) {
 	testcases := []LimitTagsTestCase{
 		{
@@ -312,6 +322,10 @@ func Test_TagsOlderThan(t *testing.T) {
 						},
 					},
 				},
+				{
+					Tag:   "undated",
+					Items: []imagev1.TagEvent{{}},
+				},
 			},
 			expected: []string{
 				"c8a693ad89e7069674eda512c553ff56d3ca2ffd-debug",
@@ -423,3 +437,117 @@ func TestFilterOrphanImageTags(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPrunableGenerations(t *testing.T) {
+	imageStreamTag := imagev1.NamedTagEventList{
+		Tag: "latest",
+		Items: []imagev1.TagEvent{
+			{Image: "sha256:d", Created: metav1.NewTime(time.Date(2020, 4, 4, 0, 0, 0, 0, time.UTC))},
+			{Image: "sha256:c", Created: metav1.NewTime(time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC))},
+			{Image: "sha256:b", Created: metav1.NewTime(time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC))},
+			{Image: "sha256:a", Created: metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))},
+		},
+	}
+	type args struct {
+		keep            int
+		keepYoungerThan time.Time
+		activeDigests   []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "ShouldKeep_NewestGenerations",
+			args: args{keep: 2, keepYoungerThan: time.Now()},
+			want: []string{"sha256:b", "sha256:a"},
+		},
+		{
+			name: "ShouldKeep_CurrentGeneration_IfKeepIsZero",
+			args: args{keep: 0, keepYoungerThan: time.Now()},
+			want: []string{"sha256:c", "sha256:b", "sha256:a"},
+		},
+		{
+			name: "ShouldKeep_YoungGenerations",
+			args: args{keep: 1, keepYoungerThan: time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC)},
+			want: []string{"sha256:a"},
+		},
+		{
+			name: "ShouldKeep_ActiveGenerations",
+			args: args{keep: 1, keepYoungerThan: time.Now(), activeDigests: []string{"sha256:b"}},
+			want: []string{"sha256:c", "sha256:a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetPrunableGenerations(imageStreamTag, tt.args.keep, tt.args.keepYoungerThan, tt.args.activeDigests)
+			var digests []string
+			for _, tagEvent := range result {
+				digests = append(digests, tagEvent.Image)
+			}
+			assert.Equal(t, tt.want, digests)
+		})
+	}
+}
+
+func TestMatchOptionPrefix_Match(t *testing.T) {
+	sha := "a3d0df2c5060b87650df6a94a0a9600510303003"
+
+	assert.True(t, MatchOptionPrefix.Match("a3d0df2", sha), "short SHA")
+	assert.True(t, MatchOptionPrefix.Match(sha, sha), "full SHA")
+	assert.True(t, MatchOptionPrefix.Match(sha+"-debug", sha), "full SHA with suffix")
+	assert.False(t, MatchOptionPrefix.Match("a3d0df3", sha), "different SHA")
+	assert.False(t, MatchOptionPrefix.Match(sha+"debug", sha), "full SHA with suffix without dash")
+	assert.False(t, MatchOptionPrefix.Match("a3d0df2-debug", sha), "short SHA with suffix")
+	assert.False(t, MatchOptionPrefix.Match(sha+"0", sha), "longer than the SHA")
+}
+
+func TestTagCommitMatcher_Match(t *testing.T) {
+	sha := "a3d0df2c5060b87650df6a94a0a9600510303003"
+	matcher := &TagCommitMatcher{Commits: map[string][]string{"v1.2": {"108f2be974f8e1e5fec8bc759ecf824e81565747", sha}}, MinLength: 7}
+
+	assert.True(t, matcher.Match("v1.2", "v1.2"), "git tag name")
+	assert.True(t, matcher.Match("a3d0df2", "v1.2"), "short SHA of tagged commit")
+	assert.True(t, matcher.Match(sha, "v1.2"), "full SHA of tagged commit")
+	assert.False(t, matcher.Match("a3d0d", "v1.2"), "SHA shorter than minimum length")
+	assert.False(t, matcher.Match("a3d0df2", "v1.3"), "other git tag")
+	assert.False(t, matcher.Match("v1.2.0", "v1.2"), "other name")
+}
+
+func TestSplitShortTags(t *testing.T) {
+	tags, shortTags := SplitShortTags([]string{"a", "a3d0df2", "a3d0df", "a3d0df2c5060b87650df6a94a0a9600510303003"}, 7)
+
+	assert.Equal(t, []string{"a3d0df2", "a3d0df2c5060b87650df6a94a0a9600510303003"}, tags)
+	assert.Equal(t, []string{"a", "a3d0df"}, shortTags)
+}
+
+func TestFindAmbiguousTags(t *testing.T) {
+	gitValues := []string{
+		"a3d0df2c5060b87650df6a94a0a9600510303003",
+		"a3d0df2f590ed7ed8be6ec0a2a87816228a482c9",
+		"108f2be974f8e1e5fec8bc759ecf824e81565747",
+	}
+	imageTags := []string{"a3d0df2", "a3d0df2c", "108f2be", "a3d0df2c5060b87650df6a94a0a9600510303003"}
+
+	result := FindAmbiguousTags(gitValues, imageTags, MatchOptionPrefix)
+
+	assert.Equal(t, []string{"a3d0df2"}, result)
+}
+
+func TestSortTagsByCreation(t *testing.T) {
+	now := time.Now()
+	imageStreamObjectTags := []imagev1.NamedTagEventList{
+		{Tag: "old", Items: []imagev1.TagEvent{{Created: metav1.NewTime(now.Add(-3 * time.Hour))}}},
+		{Tag: "retagged", Items: []imagev1.TagEvent{
+			{Created: metav1.NewTime(now.Add(-4 * time.Hour))},
+			{Created: metav1.NewTime(now.Add(-1 * time.Hour))},
+		}},
+		{Tag: "empty"},
+		{Tag: "new", Items: []imagev1.TagEvent{{Created: metav1.NewTime(now)}}},
+	}
+
+	result := SortTagsByCreation(imageStreamObjectTags)
+
+	assert.Equal(t, []string{"new", "retagged", "old", "empty"}, result)
+}
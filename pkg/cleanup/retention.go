@@ -0,0 +1,91 @@
+package cleanup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appuio/seiso/pkg/git"
+	"github.com/hashicorp/go-version"
+	log "github.com/sirupsen/logrus"
+)
+
+type (
+	// RetentionPolicy defines which version tags are kept. A tag is kept if any of the rules applies to it.
+	RetentionPolicy struct {
+		// Keep keeps the newest <Keep> tags
+		Keep int
+		// KeepMinors keeps the newest <KeepPatches> releases of each of the newest <KeepMinors> minor versions
+		KeepMinors  int
+		KeepPatches int
+		// KeepLatestPerMajor keeps the latest release of every major version
+		KeepLatestPerMajor bool
+		// PrereleaseCutOff drops pre-releases created before this time, even if another rule would keep them. Disabled
+		// if zero.
+		PrereleaseCutOff time.Time
+	}
+)
+
+// ApplyRetentionPolicy returns the tags which should not be kept according to the policy, and the rules that kept each
+// of the other tags, indexed by tag. The tags are expected to be sorted from newest to oldest, created holds the
+// creation time of each tag. Pre-releases are only considered by the Keep rule.
+func ApplyRetentionPolicy(tags []string, created map[string]time.Time, policy RetentionPolicy) ([]string, map[string][]string) {
+	kept := map[string][]string{}
+	var candidates []string
+	for _, tag := range tags {
+		createdAt, ok := created[tag]
+		if !policy.PrereleaseCutOff.IsZero() && isPrerelease(tag) && ok && createdAt.Before(policy.PrereleaseCutOff) {
+			log.WithField("tag", tag).Debug("Dropping old pre-release")
+			continue
+		}
+		candidates = append(candidates, tag)
+	}
+
+	for i, tag := range candidates {
+		if i >= policy.Keep {
+			break
+		}
+		kept[tag] = append(kept[tag], fmt.Sprintf("one of the newest %d tags", policy.Keep))
+	}
+
+	var releases []string
+	for _, tag := range candidates {
+		if !isPrerelease(tag) {
+			releases = append(releases, tag)
+		}
+	}
+	minors := 0
+	patches := 0
+	lastMinor := ""
+	majors := map[int]bool{}
+	for _, version := range git.SortVersions(releases) {
+		segments := version.Segments()
+		tag := version.Original()
+		minor := fmt.Sprintf("%d.%d", segments[0], segments[1])
+		if minor != lastMinor {
+			minors++
+			patches = 0
+			lastMinor = minor
+		}
+		patches++
+		if minors <= policy.KeepMinors && patches <= policy.KeepPatches {
+			kept[tag] = append(kept[tag], fmt.Sprintf("one of the newest %d releases of minor version %s", policy.KeepPatches, minor))
+		}
+		if policy.KeepLatestPerMajor && !majors[segments[0]] {
+			kept[tag] = append(kept[tag], fmt.Sprintf("latest release of major version %d", segments[0]))
+		}
+		majors[segments[0]] = true
+	}
+
+	var toDelete []string
+	for _, tag := range tags {
+		if _, ok := kept[tag]; !ok {
+			toDelete = append(toDelete, tag)
+		}
+	}
+	return toDelete, kept
+}
+
+func isPrerelease(tag string) bool {
+	v, err := version.NewVersion(tag)
+	return err == nil && v.Prerelease() != ""
+}
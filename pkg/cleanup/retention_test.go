@@ -0,0 +1,71 @@
+package cleanup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyRetentionPolicy(t *testing.T) {
+	now := time.Now()
+	tags := []string{"v2.1.0", "v2.1.0-rc.1", "v2.0.1", "v2.0.0", "v1.4.2", "v1.4.1", "v1.3.0", "v1.2.0", "v0.9.0"}
+	created := map[string]time.Time{
+		"v2.1.0-rc.1": now.Add(-30 * 24 * time.Hour),
+	}
+	tests := []struct {
+		name         string
+		policy       RetentionPolicy
+		wantToDelete []string
+		wantKept     map[string][]string
+	}{
+		{
+			name:         "ShouldKeepNewestTags",
+			policy:       RetentionPolicy{Keep: 2},
+			wantToDelete: []string{"v2.0.1", "v2.0.0", "v1.4.2", "v1.4.1", "v1.3.0", "v1.2.0", "v0.9.0"},
+			wantKept: map[string][]string{
+				"v2.1.0":      {"one of the newest 2 tags"},
+				"v2.1.0-rc.1": {"one of the newest 2 tags"},
+			},
+		},
+		{
+			name:         "ShouldKeepNewestPatchesOfNewestMinors",
+			policy:       RetentionPolicy{KeepMinors: 3, KeepPatches: 2},
+			wantToDelete: []string{"v2.1.0-rc.1", "v1.3.0", "v1.2.0", "v0.9.0"},
+			wantKept: map[string][]string{
+				"v2.1.0": {"one of the newest 2 releases of minor version 2.1"},
+				"v2.0.1": {"one of the newest 2 releases of minor version 2.0"},
+				"v2.0.0": {"one of the newest 2 releases of minor version 2.0"},
+				"v1.4.2": {"one of the newest 2 releases of minor version 1.4"},
+				"v1.4.1": {"one of the newest 2 releases of minor version 1.4"},
+			},
+		},
+		{
+			name:         "ShouldKeepLatestReleaseOfEveryMajor",
+			policy:       RetentionPolicy{Keep: 1, KeepLatestPerMajor: true},
+			wantToDelete: []string{"v2.1.0-rc.1", "v2.0.1", "v2.0.0", "v1.4.1", "v1.3.0", "v1.2.0"},
+			wantKept: map[string][]string{
+				"v2.1.0": {"one of the newest 1 tags", "latest release of major version 2"},
+				"v1.4.2": {"latest release of major version 1"},
+				"v0.9.0": {"latest release of major version 0"},
+			},
+		},
+		{
+			name:         "ShouldDropOldPrereleases",
+			policy:       RetentionPolicy{Keep: 2, PrereleaseCutOff: now.Add(-7 * 24 * time.Hour)},
+			wantToDelete: []string{"v2.1.0-rc.1", "v2.0.0", "v1.4.2", "v1.4.1", "v1.3.0", "v1.2.0", "v0.9.0"},
+			wantKept: map[string][]string{
+				"v2.1.0": {"one of the newest 2 tags"},
+				"v2.0.1": {"one of the newest 2 tags"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toDelete, kept := ApplyRetentionPolicy(tags, created, tt.policy)
+
+			assert.Equal(t, tt.wantToDelete, toDelete)
+			assert.Equal(t, tt.wantKept, kept)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package cleanup
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// TagPatternGroupSHA is the name of the capture group holding a (short) commit SHA
+	TagPatternGroupSHA = "sha"
+	// TagPatternGroupTag is the name of the capture group holding a git tag
+	TagPatternGroupTag = "tag"
+	// TagPatternGroupBranch is the name of the capture group holding a branch name
+	TagPatternGroupBranch = "branch"
+)
+
+// TagPattern extracts git references from image tags like "main-a3d0df2" with the named capture groups "sha", "tag"
+// and "branch"
+type TagPattern struct {
+	regexp *regexp.Regexp
+}
+
+// GitReference holds the git references extracted from an image tag. Missing references are empty.
+type GitReference struct {
+	SHA    string
+	Tag    string
+	Branch string
+}
+
+// NewTagPattern parses the regex, which needs at least one of the named capture groups
+func NewTagPattern(pattern string) (*TagPattern, error) {
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse tag pattern '%s': %w", pattern, err)
+	}
+	p := &TagPattern{regexp: r}
+	if !p.HasGroup(TagPatternGroupSHA) && !p.HasGroup(TagPatternGroupTag) && !p.HasGroup(TagPatternGroupBranch) {
+		return nil, fmt.Errorf("tag pattern '%s' has none of the named groups %s, %s or %s",
+			pattern, TagPatternGroupSHA, TagPatternGroupTag, TagPatternGroupBranch)
+	}
+	return p, nil
+}
+
+// HasGroup returns true if the pattern has a capture group with the name
+func (p *TagPattern) HasGroup(name string) bool {
+	return p.regexp.SubexpIndex(name) >= 0
+}
+
+// Parse extracts the git references from the image tag. It returns false if the image tag does not match the pattern.
+func (p *TagPattern) Parse(imageTag string) (GitReference, bool) {
+	matches := p.regexp.FindStringSubmatch(imageTag)
+	if matches == nil {
+		return GitReference{}, false
+	}
+	group := func(name string) string {
+		if i := p.regexp.SubexpIndex(name); i >= 0 {
+			return matches[i]
+		}
+		return ""
+	}
+	return GitReference{
+		SHA:    group(TagPatternGroupSHA),
+		Tag:    group(TagPatternGroupTag),
+		Branch: group(TagPatternGroupBranch),
+	}, true
+}
+
+// SplitParseable splits the image tags into the tags matching the pattern and the unparseable tags
+func (p *TagPattern) SplitParseable(imageTags []string) (parseable []string, unparseable []string) {
+	for _, tag := range imageTags {
+		if _, ok := p.Parse(tag); ok {
+			parseable = append(parseable, tag)
+		} else {
+			unparseable = append(unparseable, tag)
+		}
+	}
+	return parseable, unparseable
+}
+
+func (p *TagPattern) String() string {
+	return p.regexp.String()
+}
+
+// PatternMatcher matches image tags with git values by the references extracted with a TagPattern. When comparing
+// with git tags (Tags), the git tag must be equal to the extracted tag. Otherwise, the commit SHA (the git value) must
+// start with the extracted SHA, and the extracted branch (if any) must be one of Branches. References not relevant for
+// the comparison are ignored, so that a pattern may extract both a tag and a SHA.
+type PatternMatcher struct {
+	Pattern *TagPattern
+	// Tags is true if the git values are git tags instead of commit SHAs
+	Tags bool
+	// Branches are the branch names of the repository. Slashes in branch names also match dashes in image tags.
+	Branches []string
+}
+
+// Match returns true if the references extracted from the image tag match the git value
+func (m *PatternMatcher) Match(imageTag, gitValue string) bool {
+	ref, ok := m.Pattern.Parse(imageTag)
+	if !ok {
+		return false
+	}
+	if m.Tags {
+		return ref.Tag != "" && ref.Tag == gitValue
+	}
+	if ref.SHA == "" || !strings.HasPrefix(gitValue, ref.SHA) {
+		return false
+	}
+	return ref.Branch == "" || m.isBranch(ref.Branch)
+}
+
+func (m *PatternMatcher) isBranch(name string) bool {
+	for _, branch := range m.Branches {
+		if name == branch || name == strings.ReplaceAll(branch, "/", "-") {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *PatternMatcher) String() string {
+	return m.Pattern.String()
+}
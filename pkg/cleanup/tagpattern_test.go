@@ -0,0 +1,163 @@
+package cleanup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTagPattern(t *testing.T) {
+	_, err := NewTagPattern("^(?P<sha>[0-9a-f]+)$")
+	assert.NoError(t, err)
+	_, err = NewTagPattern("^[0-9a-f]+$")
+	assert.Error(t, err)
+	_, err = NewTagPattern("(?P<sha>")
+	assert.Error(t, err)
+}
+
+func TestTagPattern_Parse(t *testing.T) {
+	pattern, err := NewTagPattern("^(?:(?P<branch>[a-z]+)|pr-[0-9]+)-(?P<sha>[0-9a-f]{7,40})$")
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		imageTag string
+		want     GitReference
+		wantOk   bool
+	}{
+		{
+			name:     "ShouldParseBranchAndSHA",
+			imageTag: "main-a3d0df2",
+			want:     GitReference{Branch: "main", SHA: "a3d0df2"},
+			wantOk:   true,
+		},
+		{
+			name:     "ShouldParseSHA_IfGroupDoesNotParticipate",
+			imageTag: "pr-812-a3d0df2c5060b87650df6a94a0a9600510303003",
+			want:     GitReference{SHA: "a3d0df2c5060b87650df6a94a0a9600510303003"},
+			wantOk:   true,
+		},
+		{
+			name:     "ShouldNotParse_IfPatternDoesNotMatch",
+			imageTag: "latest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, ok := pattern.Parse(tt.imageTag)
+
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, ref)
+		})
+	}
+}
+
+func TestPatternMatcher_Match(t *testing.T) {
+	sha := "a3d0df2c5060b87650df6a94a0a9600510303003"
+	shaPattern, err := NewTagPattern("^(?P<branch>.+)-(?P<sha>[0-9a-f]{7,40})$")
+	require.NoError(t, err)
+	tagPattern, err := NewTagPattern("^(?P<tag>v[0-9.]+)-build\\.[0-9]+$")
+	require.NoError(t, err)
+	mixedPattern, err := NewTagPattern("^(?P<tag>v[0-9.]+)-(?P<sha>[0-9a-f]{7,40})$")
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		pattern  *TagPattern
+		tags     bool
+		imageTag string
+		gitValue string
+		want     bool
+	}{
+		{
+			name:     "ShouldMatch_IfSHAIsPrefixOfCommit",
+			pattern:  shaPattern,
+			imageTag: "main-a3d0df2",
+			gitValue: sha,
+			want:     true,
+		},
+		{
+			name:     "ShouldMatch_IfBranchHasSlash",
+			pattern:  shaPattern,
+			imageTag: "feature-login-a3d0df2",
+			gitValue: sha,
+			want:     true,
+		},
+		{
+			name:     "ShouldNotMatch_IfBranchUnknown",
+			pattern:  shaPattern,
+			imageTag: "deleted-a3d0df2",
+			gitValue: sha,
+		},
+		{
+			name:     "ShouldNotMatch_IfSHADiffers",
+			pattern:  shaPattern,
+			imageTag: "main-b3d0df2",
+			gitValue: sha,
+		},
+		{
+			name:     "ShouldMatch_IfTagEqual",
+			pattern:  tagPattern,
+			tags:     true,
+			imageTag: "v1.2.3-build.45",
+			gitValue: "v1.2.3",
+			want:     true,
+		},
+		{
+			name:     "ShouldNotMatch_IfTagDiffers",
+			pattern:  tagPattern,
+			tags:     true,
+			imageTag: "v1.2.3-build.45",
+			gitValue: "v1.2",
+		},
+		{
+			name:     "ShouldMatchSHA_IfPatternHasTagAndSHA",
+			pattern:  mixedPattern,
+			imageTag: "v1.2.3-a3d0df2",
+			gitValue: sha,
+			want:     true,
+		},
+		{
+			name:     "ShouldMatchTag_IfPatternHasTagAndSHA",
+			pattern:  mixedPattern,
+			tags:     true,
+			imageTag: "v1.2.3-a3d0df2",
+			gitValue: "v1.2.3",
+			want:     true,
+		},
+		{
+			name:     "ShouldNotMatchTag_IfComparingCommits",
+			pattern:  tagPattern,
+			imageTag: "v1.2.3-build.45",
+			gitValue: "v1.2.3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := &PatternMatcher{Pattern: tt.pattern, Tags: tt.tags, Branches: []string{"main", "feature/login"}}
+
+			assert.Equal(t, tt.want, matcher.Match(tt.imageTag, tt.gitValue))
+		})
+	}
+}
+
+func TestGetMatchingTags_TagPattern(t *testing.T) {
+	pattern, err := NewTagPattern("^(?P<tag>v[0-9.]+)-build\\.[0-9]+$")
+	require.NoError(t, err)
+	gitTags := []string{"v1.2.3", "v1.2.2"}
+	imageTags := []string{"v1.2.2-build.40", "v1.2.3-build.44", "v1.2.3-build.45", "v1.2.1-build.30"}
+
+	result := GetMatchingTags(&gitTags, &imageTags, &PatternMatcher{Pattern: pattern, Tags: true})
+
+	assert.Equal(t, []string{"v1.2.3-build.44", "v1.2.3-build.45", "v1.2.2-build.40"}, result)
+}
+
+func TestFilterOrphanImageTags_MixedTagPattern(t *testing.T) {
+	pattern, err := NewTagPattern("^(?P<tag>v[0-9.]+)-(?P<sha>[0-9a-f]{7,40})$")
+	require.NoError(t, err)
+	gitCandidates := []string{"a3d0df2c5060b87650df6a94a0a9600510303003"}
+	imageTags := []string{"v1.2.3-a3d0df2", "v1.2.2-b3d0df2"}
+
+	result := FilterOrphanImageTags(&gitCandidates, &imageTags, &PatternMatcher{Pattern: pattern})
+
+	assert.Equal(t, []string{"v1.2.2-b3d0df2"}, result)
+}
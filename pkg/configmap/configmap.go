@@ -14,7 +14,6 @@ import (
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	core "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
@@ -46,28 +45,18 @@ func (cms ConfigMapsService) List(ctx context.Context, listOptions metav1.ListOp
 	return configMaps.Items, nil
 }
 
-func (cms ConfigMapsService) GetUnused(ctx context.Context, namespace string, configMaps []v1.ConfigMap) (unusedConfigMaps []v1.ConfigMap, funcErr error) {
+func (cms ConfigMapsService) GetUnused(ctx context.Context, namespace string, configMaps []v1.ConfigMap) (unusedConfigMaps []v1.ConfigMap, err error) {
 	var usedConfigMaps []v1.ConfigMap
-	funk.ForEach(openshift.PredefinedResources, func(predefinedResource schema.GroupVersionResource) {
-		funk.ForEach(configMaps, func(resource v1.ConfigMap) {
-
-			resourceName := resource.GetName()
-
-			if funk.Contains(usedConfigMaps, resource) {
-				// already marked as existing, skip this
-				return
-			}
-			contains, err := cms.helper.ResourceContains(ctx, namespace, resourceName, predefinedResource)
-			if err != nil {
-				funcErr = err
-				return
-			}
-
-			if contains {
-				usedConfigMaps = append(usedConfigMaps, resource)
-			}
-		})
-	})
+	index := openshift.NewReferenceIndex(cms.helper, openshift.PredefinedResources)
+	for _, resource := range configMaps {
+		contains, err := index.Contains(ctx, namespace, openshift.UsageReference(kubernetes.ReferenceKindConfigMap, resource.GetName()))
+		if err != nil {
+			return configMaps, err
+		}
+		if contains {
+			usedConfigMaps = append(usedConfigMaps, resource)
+		}
+	}
 
 	for _, resource := range configMaps {
 		if !funk.Contains(usedConfigMaps, resource) {
@@ -75,7 +64,7 @@ func (cms ConfigMapsService) GetUnused(ctx context.Context, namespace string, co
 		}
 	}
 
-	return unusedConfigMaps, funcErr
+	return unusedConfigMaps, nil
 }
 
 func (cms ConfigMapsService) Delete(ctx context.Context, configMaps []v1.ConfigMap) error {
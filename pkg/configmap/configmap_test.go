@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/appuio/seiso/pkg/kubernetes"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -18,16 +19,12 @@ import (
 type HelperKubernetes struct{}
 type HelperKubernetesErr struct{}
 
-func (k *HelperKubernetes) ResourceContains(_ context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error) {
-	if value == "nameA" {
-		return false, nil
-	} else {
-		return true, nil
-	}
+func (k *HelperKubernetes) ResourceReferences(_ context.Context, namespace string, resource schema.GroupVersionResource) (kubernetes.References, error) {
+	return kubernetes.References{kubernetes.ReferenceKindConfigMap: {"nameB": {}}}, nil
 }
 
-func (k *HelperKubernetesErr) ResourceContains(_ context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error) {
-	return false, errors.New("error")
+func (k *HelperKubernetesErr) ResourceReferences(_ context.Context, namespace string, resource schema.GroupVersionResource) (kubernetes.References, error) {
+	return nil, errors.New("error")
 }
 
 var testNamespace = "testNamespace"
@@ -1,88 +1,506 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/appuio/seiso/cfg"
-
+	log "github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
 	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
+// BranchSelection selects the branches whose commits are taken into account. If nothing is selected, the commits
+// reachable from HEAD are used.
+type BranchSelection struct {
+	// Patterns are glob patterns like "release/*" matched against the names of local branches and remote-tracking
+	// branches. The remote name of a remote-tracking branch is optional, e.g. "main" matches "origin/main".
+	Patterns []string
+	// AllBranches selects all local branches
+	AllBranches bool
+	// AllRemotes selects all remote-tracking branches
+	AllRemotes bool
+}
+
+func (s BranchSelection) isEmpty() bool {
+	return len(s.Patterns) == 0 && !s.AllBranches && !s.AllRemotes
+}
+
 // GetCommitHashes returns the commit hashes of a given repository ordered by the `git.LogOrderCommitterTime`. If `commitLimit` is 0 all commits will be returned.
 func GetCommitHashes(repoPath string, commitLimit int) ([]string, error) {
-	var commitHashes []string
+	return GetCommitHashesOfBranches(repoPath, commitLimit, BranchSelection{})
+}
 
+// GetCommitHashesOfBranches returns the union of the commit hashes reachable from the selected branches, ordered by
+// committer time. If `commitLimit` is 0 all commits will be returned.
+func GetCommitHashesOfBranches(repoPath string, commitLimit int, selection BranchSelection) ([]string, error) {
 	repository, err := git.PlainOpen(repoPath)
 	if err != nil {
 		return nil, err
 	}
+	return getCommitHashes(repository, commitLimit, selection, nil)
+}
 
-	commitIter, err := repository.Log(&git.LogOptions{Order: git.LogOrderCommitterTime})
-	defer commitIter.Close()
+// getCommitHashes returns the union of the commit hashes reachable from the selected branches. If subpaths are given,
+// only commits changing files in any of these paths are returned, and `commitLimit` applies to those commits.
+func getCommitHashes(repository *git.Repository, commitLimit int, selection BranchSelection, subpaths []string) ([]string, error) {
+	heads, err := resolveBranches(repository, selection)
+	if err != nil {
+		return nil, err
+	}
+	boundary, err := repository.Storer.Shallow()
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := shallowParents(repository, boundary)
 	if err != nil {
 		return nil, err
 	}
 
-	for i := 0; i < commitLimit || commitLimit == 0; i++ {
-		commit, err := commitIter.Next()
+	subpaths = cleanSubpaths(subpaths)
+	var commits []*object.Commit
+	seen := map[plumbing.Hash]bool{}
+	for _, head := range heads {
+		headCommit, err := repository.CommitObject(head)
 		if err != nil {
-			if err == io.EOF {
-				break
+			return nil, err
+		}
+		// Each log is ordered by committer time, so the newest <commitLimit> commits of the union are among the
+		// newest <commitLimit> commits of each branch. The parents of shallow commits are missing, so they are ignored.
+		commitIter := object.NewCommitIterCTime(headCommit, nil, ignore)
+		count := 0
+		reachedBoundary := false
+		err = forEachCommit(commitIter, commitLimit, func(commit *object.Commit) (bool, error) {
+			isBoundary := funk.Contains(boundary, commit.Hash)
+			if isBoundary {
+				reachedBoundary = true
+			}
+			if len(subpaths) > 0 {
+				touched, err := touchesSubpaths(commit, subpaths, isBoundary)
+				if err != nil || !touched {
+					return false, err
+				}
+			}
+			count++
+			if !seen[commit.Hash] {
+				seen[commit.Hash] = true
+				commits = append(commits, commit)
 			}
+			return true, nil
+		})
+		if err != nil {
 			return nil, err
 		}
-		commitHashes = append(commitHashes, commit.Hash.String())
+		// A commit limit of 0 means all commits, which always reaches past the boundary
+		if reachedBoundary && (commitLimit == 0 || count < commitLimit) {
+			log.WithFields(log.Fields{
+				"commitLimit": commitLimit,
+				"commits":     count,
+				"head":        head.String(),
+			}).Warn("--commit-limit reaches past the shallow boundary of the Git repository")
+		}
+	}
+
+	if len(heads) > 1 {
+		sort.SliceStable(commits, func(i, j int) bool {
+			return commits[i].Committer.When.After(commits[j].Committer.When)
+		})
+	}
+	if commitLimit > 0 && len(commits) > commitLimit {
+		commits = commits[:commitLimit]
 	}
 
+	commitHashes := make([]string, len(commits))
+	for i, commit := range commits {
+		commitHashes[i] = commit.Hash.String()
+	}
 	return commitHashes, nil
 }
 
-// GetTags returns the commit tags of a given repository ordered alphabetically or by version. If `commitLimit` is 0 all tags will be returned.
-func GetTags(repoPath string, tagLimit int, sortTagBy SortOption) ([]string, error) {
-	var commitTags []string
+// forEachCommit calls fn for each commit until fn accepted `commitLimit` commits
+func forEachCommit(commitIter object.CommitIter, commitLimit int, fn func(commit *object.Commit) (bool, error)) error {
+	defer commitIter.Close()
+	for accepted := 0; accepted < commitLimit || commitLimit <= 0; {
+		commit, err := commitIter.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		ok, err := fn(commit)
+		if err != nil {
+			return err
+		}
+		if ok {
+			accepted++
+		}
+	}
+	return nil
+}
 
-	repository, err := git.PlainOpen(repoPath)
+// IsShallow returns true if the Git repository is a shallow clone, i.e. if it has a `.git/shallow` file or its
+// storer lists shallow commits. Repositories cloned from `RepoURL` are never shallow.
+func IsShallow(o *cfg.GitConfig) (bool, error) {
+	if o.RepoURL != "" {
+		return false, nil
+	}
+	if _, err := os.Stat(filepath.Join(o.RepoPath, ".git", "shallow")); err == nil {
+		return true, nil
+	}
+	repository, err := git.PlainOpen(o.RepoPath)
+	if err != nil {
+		return false, err
+	}
+	boundary, err := repository.Storer.Shallow()
+	if err != nil {
+		return false, err
+	}
+	return len(boundary) > 0, nil
+}
+
+// shallowParents returns the parents of the shallow commits, which are not part of the repository
+func shallowParents(repository *git.Repository, boundary []plumbing.Hash) ([]plumbing.Hash, error) {
+	var parents []plumbing.Hash
+	for _, hash := range boundary {
+		commit, err := repository.CommitObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		parents = append(parents, commit.ParentHashes...)
+	}
+	return parents, nil
+}
+
+// GetBranchNames returns the names of the local branches and remote-tracking branches (without the remote name)
+func GetBranchNames(o *cfg.GitConfig) ([]string, error) {
+	repository, err := OpenRepository(o)
+	if err != nil {
+		return nil, fmt.Errorf("opening git repository failed: %w", err)
+	}
+	refIter, err := repository.References()
 	if err != nil {
 		return nil, err
 	}
+	var branches []string
+	err = refIter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		switch {
+		case ref.Name().IsBranch():
+		case ref.Name().IsRemote():
+			parts := strings.SplitN(name, "/", 2)
+			if len(parts) < 2 || parts[1] == "HEAD" {
+				return nil
+			}
+			name = parts[1]
+		default:
+			return nil
+		}
+		if !funk.ContainsString(branches, name) {
+			branches = append(branches, name)
+		}
+		return nil
+	})
+	return branches, err
+}
 
-	tagIter, err := repository.Tags()
-	defer tagIter.Close()
+// resolveBranches returns the commit hashes the selected branches point to
+func resolveBranches(repository *git.Repository, selection BranchSelection) ([]plumbing.Hash, error) {
+	if selection.isEmpty() {
+		head, err := repository.Head()
+		if err != nil {
+			return nil, err
+		}
+		return []plumbing.Hash{head.Hash()}, nil
+	}
+
+	refIter, err := repository.References()
+	if err != nil {
+		return nil, err
+	}
+	var heads []plumbing.Hash
+	matchedPatterns := map[string]bool{}
+	err = refIter.ForEach(func(ref *plumbing.Reference) error {
+		// Symbolic references like refs/remotes/origin/HEAD point to another branch anyway
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		var selected bool
+		var names []string
+		switch {
+		case ref.Name().IsBranch():
+			selected = selection.AllBranches
+			names = []string{ref.Name().Short()}
+		case ref.Name().IsRemote():
+			selected = selection.AllRemotes
+			names = []string{ref.Name().Short()}
+			if parts := strings.SplitN(ref.Name().Short(), "/", 2); len(parts) == 2 {
+				names = append(names, parts[1])
+			}
+		default:
+			return nil
+		}
+		for _, pattern := range selection.Patterns {
+			for _, name := range names {
+				matched, err := path.Match(pattern, name)
+				if err != nil {
+					return fmt.Errorf("invalid branch pattern '%s': %w", pattern, err)
+				}
+				if matched {
+					matchedPatterns[pattern] = true
+					selected = true
+				}
+			}
+		}
+		if selected {
+			log.WithField("ref", ref.Name().String()).Debug("Using commits of branch")
+			heads = append(heads, ref.Hash())
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	for i := 0; i < tagLimit || tagLimit == 0; i++ {
-		tag, err := tagIter.Next()
+	for _, pattern := range selection.Patterns {
+		if !matchedPatterns[pattern] {
+			log.WithField("pattern", pattern).Warn("No branch matches pattern")
+		}
+	}
+	if len(heads) == 0 {
+		return nil, errors.New("no branch matches the selection")
+	}
+	return heads, nil
+}
+
+// Tag is a Git tag peeled to the commit it points to
+type Tag struct {
+	// Name is the full name of the tag without the "refs/tags/" prefix, e.g. "release/v1.2". It is used for filtering.
+	Name string
+	// Commit is the hash of the commit the tag points to
+	Commit string
+	// Date is the tagger date of annotated tags and the committer date of the commit for lightweight tags
+	Date time.Time
+}
+
+// ImageTag returns the name image tags are compared with: the last path segment of the tag name, e.g. "v1.2" for
+// "release/v1.2", as image tags cannot contain "/"
+func (t Tag) ImageTag() string {
+	return path.Base(t.Name)
+}
 
+// TagFilter selects Git tags by their full name
+type TagFilter struct {
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+}
+
+// NewTagFilter parses the include and exclude regexes. A tag is selected if it matches any include regex (or no
+// include regex is given) and none of the exclude regexes.
+func NewTagFilter(include, exclude []string) (TagFilter, error) {
+	var filter TagFilter
+	for _, pattern := range include {
+		re, err := regexp.Compile(pattern)
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, err
+			return filter, fmt.Errorf("could not parse git tag include pattern '%s': %w", pattern, err)
 		}
+		filter.Include = append(filter.Include, re)
+	}
+	for _, pattern := range exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return filter, fmt.Errorf("could not parse git tag exclude pattern '%s': %w", pattern, err)
+		}
+		filter.Exclude = append(filter.Exclude, re)
+	}
+	return filter, nil
+}
 
-		splittedPath := strings.Split(tag.Name().String(), "/")
-		tagName := splittedPath[len(splittedPath)-1]
+// Matches returns true if the tag name is selected by the filter
+func (f TagFilter) Matches(name string) bool {
+	for _, re := range f.Exclude {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, re := range f.Include {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
 
-		commitTags = append(commitTags, tagName)
+// GetTags returns the commit tags of a given repository ordered alphabetically, by version or by date. If `tagLimit` is 0 all tags will be returned,
+// otherwise the first `tagLimit` tags after sorting. The tags are returned by the name image tags are compared with, see Tag.ImageTag.
+func GetTags(repoPath string, tagLimit int, sortTagBy SortOption) ([]string, error) {
+	repository, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
 	}
+	tags, err := getTags(repository, tagLimit, sortTagBy, TagFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return imageTagNames(tags), nil
+}
 
-	return sortTags(commitTags, sortTagBy)
+func getTags(repository *git.Repository, tagLimit int, sortTagBy SortOption, filter TagFilter) ([]Tag, error) {
+	tags, err := listTags(repository, filter)
+	if err != nil {
+		return nil, err
+	}
+	if sortTagBy == SortOptionDate {
+		sortTagsByDate(tags)
+	}
+	tagNames := make([]string, len(tags))
+	tagsByName := make(map[string]Tag, len(tags))
+	for i, tag := range tags {
+		tagNames[i] = tag.Name
+		tagsByName[tag.Name] = tag
+	}
+
+	sortedNames, err := sortTags(tagNames, sortTagBy)
+	if err != nil {
+		return nil, err
+	}
+	if tagLimit > 0 && len(sortedNames) > tagLimit {
+		sortedNames = sortedNames[:tagLimit]
+	}
+	sortedTags := make([]Tag, len(sortedNames))
+	for i, name := range sortedNames {
+		sortedTags[i] = tagsByName[name]
+	}
+	return sortedTags, nil
+}
+
+// imageTagNames returns the unique image tag names of the tags, keeping their order
+func imageTagNames(tags []Tag) []string {
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.ImageTag())
+	}
+	return funk.UniqString(names)
+}
+
+// GetTagCommits returns the commits of the Git tags selected by the tag filter of the config, indexed by the name
+// image tags are compared with (see Tag.ImageTag). Several tags may have the same image tag name, e.g. "release/v1.2"
+// and "hotfix/v1.2".
+func GetTagCommits(o *cfg.GitConfig) (map[string][]string, error) {
+	repository, err := OpenRepository(o)
+	if err != nil {
+		return nil, fmt.Errorf("opening git repository failed: %w", err)
+	}
+	filter, err := NewTagFilter(o.TagInclude, o.TagExclude)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := listTags(repository, filter)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving commit tags failed: %w", err)
+	}
+	commits := map[string][]string{}
+	for _, tag := range tags {
+		commits[tag.ImageTag()] = append(commits[tag.ImageTag()], tag.Commit)
+	}
+	return commits, nil
+}
+
+// listTags returns the tags selected by the filter. Annotated tags are peeled to their commit, tags that do not point
+// to a commit are skipped.
+func listTags(repository *git.Repository, filter TagFilter) ([]Tag, error) {
+	tagIter, err := repository.Tags()
+	if err != nil {
+		return nil, err
+	}
+	defer tagIter.Close()
+
+	var tags []Tag
+	err = tagIter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		if !filter.Matches(name) {
+			log.WithField("tag", name).Debug("Skipped git tag not matching the filter")
+			return nil
+		}
+		tag, err := peelTag(repository, ref)
+		if err != nil {
+			log.WithError(err).WithField("tag", name).Warn("Skipped git tag not pointing to a commit")
+			return nil
+		}
+		tag.Name = name
+		log.WithFields(log.Fields{
+			"tag":    name,
+			"commit": tag.Commit,
+			"date":   tag.Date,
+		}).Debug("Found git tag")
+		tags = append(tags, tag)
+		return nil
+	})
+	return tags, err
+}
+
+// peelTag returns the commit and date of an annotated or lightweight tag
+func peelTag(repository *git.Repository, ref *plumbing.Reference) (Tag, error) {
+	tagObject, err := repository.TagObject(ref.Hash())
+	switch err {
+	case nil:
+		commit, err := tagObject.Commit()
+		if err != nil {
+			return Tag{}, err
+		}
+		date := tagObject.Tagger.When
+		if date.IsZero() {
+			date = commit.Committer.When
+		}
+		return Tag{Commit: commit.Hash.String(), Date: date}, nil
+	case plumbing.ErrObjectNotFound:
+		// Lightweight tags point to the commit directly
+		commit, err := repository.CommitObject(ref.Hash())
+		if err != nil {
+			return Tag{}, err
+		}
+		return Tag{Commit: commit.Hash.String(), Date: commit.Committer.When}, nil
+	default:
+		return Tag{}, err
+	}
 }
 
 // GetGitCandidateList returns either git tags or git commit SHAs
 func GetGitCandidateList(o *cfg.GitConfig) ([]string, error) {
+	repository, err := OpenRepository(o)
+	if err != nil {
+		return []string{}, fmt.Errorf("opening git repository failed: %w", err)
+	}
 	if o.Tag {
-		candidates, err := GetTags(o.RepoPath, o.CommitLimit, SortOption(o.SortCriteria))
+		filter, err := NewTagFilter(o.TagInclude, o.TagExclude)
+		if err != nil {
+			return []string{}, err
+		}
+		tags, err := getTags(repository, o.CommitLimit, SortOption(o.SortCriteria), filter)
 		if err != nil {
 			return []string{}, fmt.Errorf("retrieving commit tags failed: %w", err)
 		}
-		return candidates, nil
+		return imageTagNames(tags), nil
+	}
+	selection := BranchSelection{
+		Patterns:    o.Branches,
+		AllBranches: o.AllBranches,
+		AllRemotes:  o.AllRemotes,
 	}
-	candidates, err := GetCommitHashes(o.RepoPath, o.CommitLimit)
+	candidates, err := getCommitHashes(repository, o.CommitLimit, selection, o.RepoSubpaths)
 	if err != nil {
 		return []string{}, fmt.Errorf("retrieving commit hashes failed: %w", err)
 	}
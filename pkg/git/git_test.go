@@ -1,9 +1,20 @@
 package git
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/appuio/seiso/cfg"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 func Test_GetCommitHashes(t *testing.T) {
@@ -81,3 +92,254 @@ func Test_SortBInAlphabeticalOrder(t *testing.T) {
 	assert.NoError(t, err)
 	assert.EqualValues(t, expectedSortedTags, sortedTags)
 }
+
+// newTestRepository creates a repository with the commits "main-1" and "main-2" on master, "feature" on the branch
+// feature/a and "release" on the remote-tracking branch origin/release/1.0, each an hour apart. HEAD is detached at
+// "feature".
+func newTestRepository(t *testing.T) (string, map[string]string) {
+	dir, err := ioutil.TempDir("", "seiso-git")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	repository, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	worktree, err := repository.Worktree()
+	require.NoError(t, err)
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	hashes := map[string]string{}
+	commit := func(name string, offset int) plumbing.Hash {
+		signature := &object.Signature{Name: "test", Email: "test@example.com", When: start.Add(time.Duration(offset) * time.Hour)}
+		hash, err := worktree.Commit(name, &git.CommitOptions{Author: signature, Committer: signature})
+		require.NoError(t, err)
+		hashes[name] = hash.String()
+		return hash
+	}
+	setRef := func(name string, hash plumbing.Hash) {
+		require.NoError(t, repository.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(name), hash)))
+	}
+
+	base := commit("main-1", 0)
+	setRef("refs/heads/master", commit("main-2", 3))
+	setRef("refs/heads/master", base)
+	setRef("refs/heads/feature/a", commit("feature", 2))
+	setRef("refs/heads/master", base)
+	setRef("refs/remotes/origin/release/1.0", commit("release", 1))
+	setRef("refs/heads/master", plumbing.NewHash(hashes["main-2"]))
+	require.NoError(t, repository.Storer.SetReference(plumbing.NewSymbolicReference("refs/remotes/origin/HEAD", "refs/remotes/origin/release/1.0")))
+	setRef("HEAD", plumbing.NewHash(hashes["feature"]))
+	return dir, hashes
+}
+
+func Test_GetCommitHashesOfBranches(t *testing.T) {
+	dir, hashes := newTestRepository(t)
+	tests := []struct {
+		name        string
+		selection   BranchSelection
+		commitLimit int
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name:      "ShouldUseHead_IfNothingSelected",
+			selection: BranchSelection{},
+			want:      []string{"feature", "main-1"},
+		},
+		{
+			name:      "ShouldUseMatchingBranches",
+			selection: BranchSelection{Patterns: []string{"master", "release/*"}},
+			want:      []string{"main-2", "release", "main-1"},
+		},
+		{
+			name:      "ShouldUseAllLocalBranches",
+			selection: BranchSelection{AllBranches: true},
+			want:      []string{"main-2", "feature", "main-1"},
+		},
+		{
+			name:      "ShouldUseAllRemoteBranches",
+			selection: BranchSelection{AllRemotes: true},
+			want:      []string{"release", "main-1"},
+		},
+		{
+			name:        "ShouldLimitUnion",
+			selection:   BranchSelection{AllBranches: true, AllRemotes: true},
+			commitLimit: 3,
+			want:        []string{"main-2", "feature", "release"},
+		},
+		{
+			name:      "ShouldThrowError_IfNoBranchMatches",
+			selection: BranchSelection{Patterns: []string{"unknown"}},
+			wantErr:   true,
+		},
+		{
+			name:      "ShouldThrowError_IfPatternInvalid",
+			selection: BranchSelection{Patterns: []string{"[main"}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitHashes, err := GetCommitHashesOfBranches(dir, tt.commitLimit, tt.selection)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, hashes[name])
+			}
+			assert.Equal(t, want, commitHashes)
+		})
+	}
+}
+
+func Test_ShallowRepository(t *testing.T) {
+	dir, hashes := newTestRepository(t)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".git", "shallow"), []byte(hashes["main-2"]+"\n"), 0644))
+
+	shallow, err := IsShallow(&cfg.GitConfig{RepoPath: dir})
+	require.NoError(t, err)
+	assert.True(t, shallow)
+
+	for _, commitLimit := range []int{0, 2} {
+		hook := test.NewGlobal()
+		commitHashes, err := GetCommitHashesOfBranches(dir, commitLimit, BranchSelection{Patterns: []string{"master"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{hashes["main-2"]}, commitHashes)
+		require.NotNil(t, hook.LastEntry(), "commitLimit %d", commitLimit)
+		assert.Equal(t, log.WarnLevel, hook.LastEntry().Level)
+	}
+}
+
+func Test_IsShallowFalse(t *testing.T) {
+	dir, _ := newTestRepository(t)
+
+	shallow, err := IsShallow(&cfg.GitConfig{RepoPath: dir})
+
+	assert.NoError(t, err)
+	assert.False(t, shallow)
+}
+
+func Test_listTagsAndGetTags(t *testing.T) {
+	dir, hashes := newTestRepository(t)
+	repository, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+	// The tagger date is older than the tagged commit "release"
+	tagger := &object.Signature{Name: "test", Email: "test@example.com", When: time.Date(2020, 1, 1, 0, 30, 0, 0, time.UTC)}
+	_, err = repository.CreateTag("release/v1.2.0", plumbing.NewHash(hashes["release"]), &git.CreateTagOptions{Tagger: tagger, Message: "v1.2.0"})
+	require.NoError(t, err)
+	_, err = repository.CreateTag("v1.0.0", plumbing.NewHash(hashes["main-1"]), nil)
+	require.NoError(t, err)
+	_, err = repository.CreateTag("v2.0.0-rc.1", plumbing.NewHash(hashes["feature"]), nil)
+	require.NoError(t, err)
+	_, err = repository.CreateTag("v2.0.0", plumbing.NewHash(hashes["main-2"]), nil)
+	require.NoError(t, err)
+	commit, err := repository.CommitObject(plumbing.NewHash(hashes["main-1"]))
+	require.NoError(t, err)
+	_, err = repository.CreateTag("tree", commit.TreeHash, nil)
+	require.NoError(t, err)
+
+	t.Run("ShouldPeelAnnotatedTags", func(t *testing.T) {
+		tags, err := listTags(repository, TagFilter{})
+
+		require.NoError(t, err)
+		commits := map[string]string{}
+		for _, tag := range tags {
+			commits[tag.Name] = tag.Commit
+		}
+		assert.Equal(t, map[string]string{
+			"release/v1.2.0": hashes["release"],
+			"v1.0.0":         hashes["main-1"],
+			"v2.0.0-rc.1":    hashes["feature"],
+			"v2.0.0":         hashes["main-2"],
+		}, commits)
+	})
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		tagLimit int
+		sort     SortOption
+		want     []string
+	}{
+		{
+			name: "ShouldUseLastPathSegmentOfTagNames",
+			sort: SortOptionVersion,
+			want: []string{"v2.0.0", "v2.0.0-rc.1", "v1.2.0", "v1.0.0"},
+		},
+		{
+			name:     "ShouldLimitAfterSorting",
+			tagLimit: 2,
+			sort:     SortOptionVersion,
+			want:     []string{"v2.0.0", "v2.0.0-rc.1"},
+		},
+		{
+			name:    "ShouldFilterTags",
+			include: []string{"^v"},
+			exclude: []string{"-rc\\.[0-9]+$"},
+			sort:    SortOptionVersion,
+			want:    []string{"v2.0.0", "v1.0.0"},
+		},
+		{
+			name: "ShouldSortByTaggerOrCommitterDate",
+			sort: SortOptionDate,
+			want: []string{"v2.0.0", "v2.0.0-rc.1", "v1.2.0", "v1.0.0"},
+		},
+		{
+			name:     "ShouldLimitAfterSortingByDate",
+			tagLimit: 3,
+			sort:     SortOptionDate,
+			want:     []string{"v2.0.0", "v2.0.0-rc.1", "v1.2.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewTagFilter(tt.include, tt.exclude)
+			require.NoError(t, err)
+
+			tags, err := getTags(repository, tt.tagLimit, tt.sort, filter)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, imageTagNames(tags))
+		})
+	}
+
+	t.Run("ShouldFilterByFullName", func(t *testing.T) {
+		filter, err := NewTagFilter([]string{"^release/"}, nil)
+		require.NoError(t, err)
+
+		tags, err := getTags(repository, 0, SortOptionVersion, filter)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.2.0"}, imageTagNames(tags))
+	})
+
+	t.Run("ShouldReturnTagCommitsByImageTagName", func(t *testing.T) {
+		commits, err := GetTagCommits(&cfg.GitConfig{RepoPath: dir, TagExclude: []string{"-rc"}})
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"v1.2.0": {hashes["release"]},
+			"v1.0.0": {hashes["main-1"]},
+			"v2.0.0": {hashes["main-2"]},
+		}, commits)
+	})
+}
+
+func Test_NewTagFilterFail(t *testing.T) {
+	_, err := NewTagFilter([]string{"(invalid"}, nil)
+
+	assert.Error(t, err)
+}
+
+func Test_GetBranchNames(t *testing.T) {
+	dir, _ := newTestRepository(t)
+
+	branches, err := GetBranchNames(&cfg.GitConfig{RepoPath: dir})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"master", "feature/a", "release/1.0"}, branches)
+}
@@ -0,0 +1,93 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/appuio/seiso/cfg"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// clones holds the repositories cloned into memory by URL and branch, so that each repository is only cloned once
+var clones = map[string]*git.Repository{}
+
+// OpenRepository clones the repository at `RepoURL` into memory if set, otherwise it opens the local repository at
+// `RepoPath`.
+func OpenRepository(o *cfg.GitConfig) (*git.Repository, error) {
+	if o.RepoURL == "" {
+		return git.PlainOpen(o.RepoPath)
+	}
+	key := o.RepoURL + "#" + o.RepoBranch
+	if repository, ok := clones[key]; ok {
+		return repository, nil
+	}
+	repository, err := cloneRepository(o)
+	if err != nil {
+		return nil, err
+	}
+	clones[key] = repository
+	return repository, nil
+}
+
+func cloneRepository(o *cfg.GitConfig) (*git.Repository, error) {
+	auth, err := newAuth(o)
+	if err != nil {
+		return nil, err
+	}
+	options := &git.CloneOptions{
+		URL:  o.RepoURL,
+		Auth: auth,
+		Tags: git.AllTags,
+	}
+	if o.RepoBranch != "" {
+		options.ReferenceName = plumbing.NewBranchReferenceName(o.RepoBranch)
+		options.SingleBranch = true
+	}
+
+	log.WithFields(log.Fields{
+		"url":    o.RepoURL,
+		"branch": o.RepoBranch,
+	}).Info("Cloning git repository into memory")
+	repository, err := git.Clone(memory.NewStorage(), nil, options)
+	if err != nil {
+		return nil, fmt.Errorf("could not clone '%s': %w", o.RepoURL, err)
+	}
+	return repository, nil
+}
+
+// newAuth returns the authentication method for the configured credentials, or nil if none are configured
+func newAuth(o *cfg.GitConfig) (transport.AuthMethod, error) {
+	if o.RepoSSHKey != "" && o.RepoPassword != "" {
+		return nil, errors.New("either a password or an SSH key can be used to authenticate, not both")
+	}
+	if o.RepoSSHKey != "" {
+		endpoint, err := transport.NewEndpoint(o.RepoURL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse repository URL: %w", err)
+		}
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+		auth, err := ssh.NewPublicKeysFromFile(user, o.RepoSSHKey, o.RepoSSHKeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("could not read SSH key: %w", err)
+		}
+		return auth, nil
+	}
+	if o.RepoPassword != "" {
+		user := o.RepoUsername
+		if user == "" {
+			// Most Git servers accept any user name with an access token as password
+			user = "seiso"
+		}
+		return &http.BasicAuth{Username: user, Password: o.RepoPassword}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,140 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	gitconfig "gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+// newBareTestRepository creates a bare copy of the test repository with HEAD pointing to master and the tag v1.0.0
+func newBareTestRepository(t *testing.T) (string, map[string]string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is required for the file transport")
+	}
+	dir, hashes := newTestRepository(t)
+	bareDir, err := ioutil.TempDir("", "seiso-git-bare")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(bareDir) })
+
+	repository, err := git.PlainInit(bareDir, true)
+	require.NoError(t, err)
+	_, err = repository.CreateRemote(&gitconfig.RemoteConfig{Name: "source", URLs: []string{dir}})
+	require.NoError(t, err)
+	err = repository.Fetch(&git.FetchOptions{
+		RemoteName: "source",
+		RefSpecs:   []gitconfig.RefSpec{"refs/heads/*:refs/heads/*", "refs/remotes/origin/*:refs/heads/*"},
+	})
+	require.NoError(t, err)
+	_, err = repository.CreateTag("v1.0.0", plumbing.NewHash(hashes["main-1"]), nil)
+	require.NoError(t, err)
+	require.NoError(t, repository.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/master")))
+	return bareDir, hashes
+}
+
+func Test_GetGitCandidateList_RepoURL(t *testing.T) {
+	dir, hashes := newBareTestRepository(t)
+	tests := []struct {
+		name    string
+		config  cfg.GitConfig
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "ShouldCloneDefaultBranch",
+			config: cfg.GitConfig{RepoURL: "file://" + dir},
+			want:   []string{hashes["main-2"], hashes["main-1"]},
+		},
+		{
+			name:   "ShouldCloneBranch",
+			config: cfg.GitConfig{RepoURL: "file://" + dir, RepoBranch: "feature/a"},
+			want:   []string{hashes["feature"], hashes["main-1"]},
+		},
+		{
+			name:   "ShouldCloneAllBranches",
+			config: cfg.GitConfig{RepoURL: "file://" + dir, AllRemotes: true},
+			want:   []string{hashes["main-2"], hashes["feature"], hashes["release"], hashes["main-1"]},
+		},
+		{
+			name:   "ShouldCloneTags",
+			config: cfg.GitConfig{RepoURL: "file://" + dir, Tag: true, SortCriteria: "version"},
+			want:   []string{"v1.0.0"},
+		},
+		{
+			name:    "ShouldThrowError_IfBranchNotFound",
+			config:  cfg.GitConfig{RepoURL: "file://" + dir, RepoBranch: "unknown"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, err := GetGitCandidateList(&tt.config)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, candidates)
+		})
+	}
+}
+
+func Test_newAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  cfg.GitConfig
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:   "ShouldReturnNil_IfNoCredentials",
+			config: cfg.GitConfig{RepoURL: "https://git.example.com/app.git"},
+			want:   nil,
+		},
+		{
+			name:   "ShouldUseBasicAuth_IfPasswordGiven",
+			config: cfg.GitConfig{RepoURL: "https://git.example.com/app.git", RepoUsername: "user", RepoPassword: "secret"},
+			want:   &http.BasicAuth{Username: "user", Password: "secret"},
+		},
+		{
+			name:   "ShouldUseDefaultUser_IfOnlyTokenGiven",
+			config: cfg.GitConfig{RepoURL: "https://git.example.com/app.git", RepoPassword: "token"},
+			want:   &http.BasicAuth{Username: "seiso", Password: "token"},
+		},
+		{
+			name:    "ShouldThrowError_IfPasswordAndSSHKeyGiven",
+			config:  cfg.GitConfig{RepoURL: "ssh://git@git.example.com/app.git", RepoPassword: "secret", RepoSSHKey: "id_rsa"},
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfSSHKeyNotFound",
+			config:  cfg.GitConfig{RepoURL: "ssh://git@git.example.com/app.git", RepoSSHKey: "not-a-file"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := newAuth(&tt.config)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Nil(t, auth)
+				return
+			}
+			assert.Equal(t, tt.want, auth)
+		})
+	}
+}
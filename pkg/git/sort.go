@@ -2,6 +2,7 @@ package git
 
 import (
 	"errors"
+	"path"
 	"sort"
 
 	"github.com/hashicorp/go-version"
@@ -16,11 +17,34 @@ const (
 	SortOptionVersion SortOption = "version"
 	// SortOptionAlphabetic sorts in alphabetical order
 	SortOptionAlphabetic SortOption = "alphabetic"
+	// SortOptionDate sorts by tag date, newest first
+	SortOptionDate SortOption = "date"
 )
 
 // IsValidSortValue function tries to cast the string to SortedTagBy type
 func IsValidSortValue(sortValue string) bool {
-	return SortOption(sortValue) == SortOptionVersion || SortOption(sortValue) == SortOptionAlphabetic
+	switch SortOption(sortValue) {
+	case SortOptionVersion, SortOptionAlphabetic, SortOptionDate:
+		return true
+	}
+	return false
+}
+
+// SortVersions parses the tags as versions and returns them sorted from newest to oldest. Tags that are not a valid
+// version are skipped.
+func SortVersions(tags []string) []*version.Version {
+	var versionTags []*version.Version
+	for _, raw := range tags {
+		version, err := version.NewVersion(raw)
+		if err != nil {
+			log.WithError(err).WithField("tag", raw).Warn("Skipped invalid version")
+		} else {
+			versionTags = append(versionTags, version)
+		}
+	}
+
+	sort.Sort(sort.Reverse(version.Collection(versionTags)))
+	return versionTags
 }
 
 // Sort function sorts the slice according to the sort type
@@ -28,30 +52,55 @@ func sortTags(tags []string, sortTagBy SortOption) ([]string, error) {
 	switch sortTagBy {
 
 	case SortOptionVersion:
-		var versionTags []*version.Version
-		for _, raw := range tags {
-			version, err := version.NewVersion(raw)
-			if err != nil {
-				log.WithError(err).WithField("tag", raw).Warn("Skipped invalid version")
-			} else {
-				versionTags = append(versionTags, version)
-			}
-		}
-
-		sort.Sort(sort.Reverse(version.Collection(versionTags)))
-
-		sortedTags := make([]string, len(versionTags))
-		for i, sortedVersion := range versionTags {
-			sortedTags[i] = sortedVersion.Original()
-		}
-
-		return sortedTags, nil
+		return sortTagsByVersion(tags), nil
 
 	case SortOptionAlphabetic:
 		sort.Strings(tags)
 		return tags, nil
 
+	case SortOptionDate:
+		// The dates are not known here, tags are expected to be sorted by sortTagsByDate already
+		return tags, nil
+
 	default:
 		return nil, errors.New("Undefined sort type")
 	}
 }
+
+// sortTagsByVersion sorts the tags from newest to oldest version. The version is parsed from the last path segment of
+// the tag name, e.g. "release/v1.2" is version 1.2, but the full tag name is returned. Tags that are not a valid version
+// are skipped.
+func sortTagsByVersion(tags []string) []string {
+	type versionTag struct {
+		name    string
+		version *version.Version
+	}
+	var versionTags []versionTag
+	for _, tag := range tags {
+		v, err := version.NewVersion(path.Base(tag))
+		if err != nil {
+			log.WithError(err).WithField("tag", tag).Warn("Skipped invalid version")
+			continue
+		}
+		versionTags = append(versionTags, versionTag{name: tag, version: v})
+	}
+
+	sort.SliceStable(versionTags, func(i, j int) bool {
+		return versionTags[i].version.GreaterThan(versionTags[j].version)
+	})
+	sortedTags := make([]string, len(versionTags))
+	for i, tag := range versionTags {
+		sortedTags[i] = tag.name
+	}
+	return sortedTags
+}
+
+// sortTagsByDate sorts the tags from newest to oldest date. Tags with the same date are sorted by name.
+func sortTagsByDate(tags []Tag) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		if tags[i].Date.Equal(tags[j].Date) {
+			return tags[i].Name < tags[j].Name
+		}
+		return tags[i].Date.After(tags[j].Date)
+	})
+}
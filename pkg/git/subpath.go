@@ -0,0 +1,70 @@
+package git
+
+import (
+	"path"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// cleanSubpaths normalizes the subpaths relative to the repository root. Paths pointing to the root are dropped, as
+// every commit changes the root.
+func cleanSubpaths(subpaths []string) []string {
+	var cleaned []string
+	for _, subpath := range subpaths {
+		subpath = strings.Trim(path.Clean("/"+subpath), "/")
+		if subpath != "" {
+			cleaned = append(cleaned, subpath)
+		}
+	}
+	return cleaned
+}
+
+// touchesSubpaths returns true if the commit changed a file in any of the subpaths. Like `git log -- <path>`, a merge
+// commit only changed a path if it differs from every parent. The parents of shallow commits are missing, so these
+// commits changed a path if it exists.
+func touchesSubpaths(commit *object.Commit, subpaths []string, isShallow bool) (bool, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return false, err
+	}
+	var parentTrees []*object.Tree
+	if !isShallow {
+		err = commit.Parents().ForEach(func(parent *object.Commit) error {
+			parentTree, err := parent.Tree()
+			parentTrees = append(parentTrees, parentTree)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, subpath := range subpaths {
+		hash := entryHash(tree, subpath)
+		if len(parentTrees) == 0 && hash != plumbing.ZeroHash {
+			return true, nil
+		}
+		changed := len(parentTrees) > 0
+		for _, parentTree := range parentTrees {
+			if entryHash(parentTree, subpath) == hash {
+				changed = false
+				break
+			}
+		}
+		if changed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// entryHash returns the hash of the file or directory at the path, or the zero hash if it does not exist
+func entryHash(tree *object.Tree, subpath string) plumbing.Hash {
+	entry, err := tree.FindEntry(subpath)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return entry.Hash
+}
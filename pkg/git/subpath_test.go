@@ -0,0 +1,105 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// newMonorepo creates a repository with the services "api" and "web". The branch "side" changes the api and is merged
+// into master with the change of "side".
+func newMonorepo(t *testing.T) (string, map[string]string) {
+	dir, err := ioutil.TempDir("", "seiso-monorepo")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	repository, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	worktree, err := repository.Worktree()
+	require.NoError(t, err)
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	hashes := map[string]string{}
+	commit := func(name string, offset time.Duration, files map[string]string, parents ...string) {
+		for file, content := range files {
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+			_, err := worktree.Add(file)
+			require.NoError(t, err)
+		}
+		var parentHashes []plumbing.Hash
+		for _, parent := range parents {
+			parentHashes = append(parentHashes, plumbing.NewHash(hashes[parent]))
+		}
+		signature := &object.Signature{Name: "test", Email: "test@example.com", When: start.Add(offset)}
+		hash, err := worktree.Commit(name, &git.CommitOptions{Author: signature, Committer: signature, Parents: parentHashes})
+		require.NoError(t, err)
+		hashes[name] = hash.String()
+	}
+
+	commit("init", 0, map[string]string{"services/api/main.go": "v1", "services/web/index.html": "v1"})
+	commit("web", time.Hour, map[string]string{"services/web/index.html": "v2"}, "init")
+	commit("api", 2*time.Hour, map[string]string{"services/api/main.go": "v2"}, "web")
+	commit("side", 150*time.Minute, map[string]string{"services/api/main.go": "side", "services/web/index.html": "v1"}, "init")
+	commit("readme", 3*time.Hour, map[string]string{"services/api/main.go": "v2", "services/web/index.html": "v2", "README.md": "v1"}, "api")
+	commit("merge", 4*time.Hour, map[string]string{"services/api/main.go": "side"}, "readme", "side")
+	return dir, hashes
+}
+
+func Test_getCommitHashesOfSubpaths(t *testing.T) {
+	dir, hashes := newMonorepo(t)
+	repository, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+	tests := []struct {
+		name        string
+		subpaths    []string
+		commitLimit int
+		want        []string
+	}{
+		{
+			name: "ShouldReturnAllCommits_IfNoSubpath",
+			want: []string{"merge", "readme", "side", "api", "web", "init"},
+		},
+		{
+			name:     "ShouldReturnCommitsChangingSubpath",
+			subpaths: []string{"services/api"},
+			want:     []string{"side", "api", "init"},
+		},
+		{
+			name:     "ShouldNormalizeSubpaths",
+			subpaths: []string{"./services/web/"},
+			want:     []string{"web", "init"},
+		},
+		{
+			name:     "ShouldReturnCommitsChangingAnySubpath",
+			subpaths: []string{"services/web", "README.md"},
+			want:     []string{"readme", "web", "init"},
+		},
+		{
+			name:        "ShouldLimitCommitsChangingSubpath",
+			subpaths:    []string{"services/api"},
+			commitLimit: 2,
+			want:        []string{"side", "api"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitHashes, err := getCommitHashes(repository, tt.commitLimit, BranchSelection{}, tt.subpaths)
+
+			require.NoError(t, err)
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, hashes[name])
+			}
+			assert.Equal(t, want, commitHashes)
+		})
+	}
+}
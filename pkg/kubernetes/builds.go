@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/thoas/go-funk"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	buildConfigAnnotation = "openshift.io/build-config.name"
+	buildNumberAnnotation = "openshift.io/build.number"
+)
+
+var (
+	// activeBuildPhases are the phases of Builds that have not finished yet
+	activeBuildPhases = []string{"New", "Pending", "Running"}
+	// buildImageKinds are the kinds of object references to images used by BuildConfigs and Builds
+	buildImageKinds = []string{"ImageStreamTag", "ImageStreamImage", "DockerImage"}
+)
+
+// BuildImageReferences collects the images referenced by the BuildConfigs or Builds in the list: the builder images
+// of the strategies (spec.strategy.*.from), the output (spec.output.to) and the images of image change triggers
+// (spec.triggers[].imageChange.from). Each image keeps the field referencing it as source.
+func BuildImageReferences(unstructuredList *unstructured.UnstructuredList) References {
+	refs := References{}
+	for _, item := range unstructuredList.Items {
+		strategy, _, _ := unstructured.NestedMap(item.Object, "spec", "strategy")
+		for name, value := range strategy {
+			strategyMap, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			from, _, _ := unstructured.NestedMap(strategyMap, "from")
+			addImageObjectReference(refs, item, from, fmt.Sprintf("spec.strategy.%s.from", name), buildImageKinds...)
+		}
+
+		to, _, _ := unstructured.NestedMap(item.Object, "spec", "output", "to")
+		addImageObjectReference(refs, item, to, "spec.output.to", buildImageKinds...)
+
+		triggers, _, _ := unstructured.NestedSlice(item.Object, "spec", "triggers")
+		for i, trigger := range triggers {
+			triggerMap, ok := trigger.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// an image change trigger without "from" refers to the builder image of the strategy
+			from, _, _ := unstructured.NestedMap(triggerMap, "imageChange", "from")
+			addImageObjectReference(refs, item, from, fmt.Sprintf("spec.triggers[%d].imageChange.from", i), buildImageKinds...)
+		}
+	}
+	return refs
+}
+
+// RecentBuilds returns the Builds of the list that have not finished yet, as well as the latest finished Build of each
+// BuildConfig. Older Builds are history and don't count as consumers of images.
+func RecentBuilds(buildList *unstructured.UnstructuredList) *unstructured.UnstructuredList {
+	recent := &unstructured.UnstructuredList{Object: buildList.Object}
+	latest := map[string]unstructured.Unstructured{}
+	latestNumbers := map[string]int{}
+	for _, build := range buildList.Items {
+		phase, _, _ := unstructured.NestedString(build.Object, "status", "phase")
+		if funk.ContainsString(activeBuildPhases, phase) {
+			recent.Items = append(recent.Items, build)
+			continue
+		}
+		buildConfig := build.GetAnnotations()[buildConfigAnnotation]
+		number, err := strconv.Atoi(build.GetAnnotations()[buildNumberAnnotation])
+		if buildConfig == "" || err != nil {
+			continue
+		}
+		if previous, ok := latestNumbers[buildConfig]; !ok || number > previous {
+			latestNumbers[buildConfig] = number
+			latest[buildConfig] = build
+		}
+	}
+	buildConfigs := make([]string, 0, len(latest))
+	for buildConfig := range latest {
+		buildConfigs = append(buildConfigs, buildConfig)
+	}
+	sort.Strings(buildConfigs)
+	for _, buildConfig := range buildConfigs {
+		recent.Items = append(recent.Items, latest[buildConfig])
+	}
+	return recent
+}
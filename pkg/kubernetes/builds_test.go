@@ -0,0 +1,99 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestBuildImageReferences(t *testing.T) {
+	list := &unstructured.UnstructuredList{
+		Items: []unstructured.Unstructured{
+			{Object: map[string]interface{}{
+				"kind":     "BuildConfig",
+				"metadata": map[string]interface{}{"name": "app", "namespace": "app-ci"},
+				"spec": map[string]interface{}{
+					"strategy": map[string]interface{}{
+						"type": "Source",
+						"sourceStrategy": map[string]interface{}{
+							"from": map[string]interface{}{"kind": "ImageStreamTag", "name": "builder:1.2", "namespace": "openshift"},
+						},
+					},
+					"output": map[string]interface{}{
+						"to": map[string]interface{}{"kind": "ImageStreamTag", "name": "app:latest"},
+					},
+					"triggers": []interface{}{
+						map[string]interface{}{"type": "ImageChange", "imageChange": map[string]interface{}{}},
+						map[string]interface{}{"type": "ImageChange", "imageChange": map[string]interface{}{
+							"from": map[string]interface{}{"kind": "ImageStreamTag", "name": "base:stable", "namespace": "app-ci"},
+						}},
+					},
+				},
+			}},
+			{Object: map[string]interface{}{
+				"kind":     "Build",
+				"metadata": map[string]interface{}{"name": "web-1", "namespace": "app-ci"},
+				"spec": map[string]interface{}{
+					"strategy": map[string]interface{}{
+						"dockerStrategy": map[string]interface{}{
+							"from": map[string]interface{}{"kind": "DockerImage", "name": "registry.example.com/app-ci/base@sha256:a"},
+						},
+					},
+					"output": map[string]interface{}{
+						"to": map[string]interface{}{"kind": "DockerImage", "name": "registry.example.com/app-ci/web:latest"},
+					},
+				},
+			}},
+		},
+	}
+
+	refs := BuildImageReferences(list)
+
+	assert.Equal(t, []string{
+		"app:latest",
+		"base:stable",
+		"openshift/builder:1.2",
+		"registry.example.com/app-ci/base@sha256:a",
+		"registry.example.com/app-ci/web:latest",
+	}, refs.Values(ReferenceKindImage))
+	assert.Equal(t, []string{"app-ci/BuildConfig/app spec.strategy.sourceStrategy.from"},
+		refs.Sources(Reference{Kind: ReferenceKindImage, Value: "builder:1.2"}))
+	assert.Equal(t, []string{"app-ci/BuildConfig/app spec.triggers[1].imageChange.from"},
+		refs.Sources(Reference{Kind: ReferenceKindImage, Value: "base:stable"}))
+	assert.Equal(t, []string{"app-ci/Build/web-1 spec.strategy.dockerStrategy.from"},
+		refs.Sources(Reference{Kind: ReferenceKindImage, Value: "base@sha256:a"}))
+	assert.Equal(t, []string{"app-ci/BuildConfig/app spec.output.to"},
+		refs.Sources(Reference{Kind: ReferenceKindAny, Value: "app:latest"}))
+}
+
+func TestRecentBuilds(t *testing.T) {
+	build := func(name, buildConfig, number, phase string) unstructured.Unstructured {
+		item := unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name},
+			"status":   map[string]interface{}{"phase": phase},
+		}}
+		if buildConfig != "" {
+			item.SetAnnotations(map[string]string{buildConfigAnnotation: buildConfig, buildNumberAnnotation: number})
+		}
+		return item
+	}
+	list := &unstructured.UnstructuredList{
+		Items: []unstructured.Unstructured{
+			build("web-1", "web", "1", "Complete"),
+			build("web-10", "web", "10", "Failed"),
+			build("web-9", "web", "9", "Complete"),
+			build("web-11", "web", "11", "Running"),
+			build("app-3", "app", "3", "Complete"),
+			build("manual", "", "", "Complete"),
+			build("manual-pending", "", "", "Pending"),
+		},
+	}
+
+	var names []string
+	for _, item := range RecentBuilds(list).Items {
+		names = append(names, item.GetName())
+	}
+
+	assert.Equal(t, []string{"web-11", "manual-pending", "app-3", "web-10"}, names)
+}
@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type (
+	// ReferenceIndex keeps the references of all objects of the given resources in memory, so that each resource is
+	// listed only once per namespace, no matter how many references are looked up
+	ReferenceIndex struct {
+		helper            Kubernetes
+		resources         []schema.GroupVersionResource
+		optionalResources []schema.GroupVersionResource
+		unserved          map[schema.GroupVersionResource]bool
+		namespaces        map[string]References
+	}
+)
+
+// NewReferenceIndex creates an empty index for the given resources
+func NewReferenceIndex(helper Kubernetes, resources []schema.GroupVersionResource) *ReferenceIndex {
+	return &ReferenceIndex{
+		helper:     helper,
+		resources:  resources,
+		unserved:   map[schema.GroupVersionResource]bool{},
+		namespaces: map[string]References{},
+	}
+}
+
+// WithOptionalResources adds resources to the index that are skipped if the API server does not serve them, e.g.
+// OpenShift resources on other Kubernetes distributions
+func (idx *ReferenceIndex) WithOptionalResources(resources ...schema.GroupVersionResource) *ReferenceIndex {
+	idx.optionalResources = append(idx.optionalResources, resources...)
+	return idx
+}
+
+// Contains evaluates if any object of the resources in the namespace contains the given reference
+func (idx *ReferenceIndex) Contains(ctx context.Context, namespace string, reference Reference) (bool, error) {
+	refs, err := idx.References(ctx, namespace)
+	if err != nil {
+		return false, err
+	}
+	return refs.Contains(reference), nil
+}
+
+// Sources returns the fields of the objects in the namespace referencing the given reference, as far as they are known
+func (idx *ReferenceIndex) Sources(ctx context.Context, namespace string, reference Reference) ([]string, error) {
+	refs, err := idx.References(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return refs.Sources(reference), nil
+}
+
+// ImageDigests returns the image digests run by the Pods of the namespace. They are only known if Pods are part of the
+// resources.
+func (idx *ReferenceIndex) ImageDigests(ctx context.Context, namespace string) ([]string, error) {
+	refs, err := idx.References(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return refs.Values(ReferenceKindPodImageDigest), nil
+}
+
+// References returns the references of all objects of the resources in the namespace. The resources are listed on
+// the first call for a namespace only.
+func (idx *ReferenceIndex) References(ctx context.Context, namespace string) (References, error) {
+	if refs, ok := idx.namespaces[namespace]; ok {
+		return refs, nil
+	}
+	refs := References{}
+	for _, resource := range idx.resources {
+		resourceRefs, err := idx.helper.ResourceReferences(ctx, namespace, resource)
+		if err != nil {
+			return nil, err
+		}
+		refs.Merge(resourceRefs)
+	}
+	for _, resource := range idx.optionalResources {
+		if idx.unserved[resource] {
+			continue
+		}
+		resourceRefs, err := idx.helper.ResourceReferences(ctx, namespace, resource)
+		if IsResourceNotServed(err) {
+			log.WithField("resource", FormatResource(resource)).Debug("Resource is not served by the API server, skipping")
+			idx.unserved[resource] = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		refs.Merge(resourceRefs)
+	}
+	log.WithFields(log.Fields{
+		"namespace":  namespace,
+		"images":     len(refs[ReferenceKindImage]),
+		"configMaps": len(refs[ReferenceKindConfigMap]),
+		"secrets":    len(refs[ReferenceKindSecret]),
+	}).Debug("Indexed references")
+	idx.namespaces[namespace] = refs
+	return refs, nil
+}
+
+// IsResourceNotServed evaluates if the error tells that the API server does not know the resource
+func IsResourceNotServed(err error) bool {
+	return err != nil && (apierrors.IsNotFound(err) || meta.IsNoMatchError(err))
+}
@@ -0,0 +1,93 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type countingHelper struct {
+	calls map[schema.GroupVersionResource]int
+}
+
+func (h *countingHelper) ResourceReferences(_ context.Context, _ string, resource schema.GroupVersionResource) (References, error) {
+	h.calls[resource]++
+	refs := References{}
+	if resource == podResource {
+		refs.add(ReferenceKindImage, "namespace/pod:1")
+		refs.add(ReferenceKindPodImageDigest, "sha256:a")
+		return refs, nil
+	}
+	refs.add(ReferenceKindConfigMap, "config")
+	return refs, nil
+}
+
+func TestReferenceIndex_ShouldListEachResourceOnce(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	helper := &countingHelper{calls: map[schema.GroupVersionResource]int{}}
+	index := NewReferenceIndex(helper, []schema.GroupVersionResource{podResource, deployments})
+
+	for _, name := range []string{"config", "other", "config"} {
+		_, err := index.Contains(context.Background(), "namespace", Reference{Kind: ReferenceKindConfigMap, Value: name})
+		require.NoError(t, err)
+	}
+	digests, err := index.ImageDigests(context.Background(), "namespace")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"sha256:a"}, digests)
+	assert.Equal(t, map[schema.GroupVersionResource]int{podResource: 1, deployments: 1}, helper.calls)
+}
+
+func TestReferenceIndex_ShouldNotListPods_IfNotIncluded(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	helper := &countingHelper{calls: map[schema.GroupVersionResource]int{}}
+	index := NewReferenceIndex(helper, []schema.GroupVersionResource{deployments})
+
+	contains, err := index.Contains(context.Background(), "namespace", Reference{Kind: ReferenceKindImage, Value: "pod:1"})
+	require.NoError(t, err)
+	digests, err := index.ImageDigests(context.Background(), "namespace")
+	require.NoError(t, err)
+
+	assert.False(t, contains)
+	assert.Empty(t, digests)
+	assert.Equal(t, map[schema.GroupVersionResource]int{deployments: 1}, helper.calls)
+}
+
+type unservedHelper struct {
+	countingHelper
+	unserved schema.GroupVersionResource
+}
+
+func (h *unservedHelper) ResourceReferences(ctx context.Context, namespace string, resource schema.GroupVersionResource) (References, error) {
+	if resource == h.unserved {
+		h.calls[resource]++
+		return nil, apierrors.NewNotFound(resource.GroupResource(), "")
+	}
+	return h.countingHelper.ResourceReferences(ctx, namespace, resource)
+}
+
+func TestReferenceIndex_ShouldSkipOptionalResources_IfNotServed(t *testing.T) {
+	helper := &unservedHelper{countingHelper: countingHelper{calls: map[schema.GroupVersionResource]int{}}, unserved: ImageStreamResource}
+	index := NewReferenceIndex(helper, []schema.GroupVersionResource{podResource}).WithOptionalResources(ImageStreamResource, BuildResource)
+
+	for _, namespace := range []string{"a", "b"} {
+		contains, err := index.Contains(context.Background(), namespace, Reference{Kind: ReferenceKindConfigMap, Value: "config"})
+		require.NoError(t, err)
+		assert.True(t, contains)
+	}
+
+	assert.Equal(t, map[schema.GroupVersionResource]int{podResource: 2, ImageStreamResource: 1, BuildResource: 2}, helper.calls)
+}
+
+func TestReferenceIndex_ShouldThrowError_IfRequiredResourceNotServed(t *testing.T) {
+	helper := &unservedHelper{countingHelper: countingHelper{calls: map[schema.GroupVersionResource]int{}}, unserved: ImageStreamResource}
+	index := NewReferenceIndex(helper, []schema.GroupVersionResource{ImageStreamResource})
+
+	_, err := index.References(context.Background(), "namespace")
+
+	assert.Error(t, err)
+}
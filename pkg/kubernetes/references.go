@@ -0,0 +1,280 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/thoas/go-funk"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// ReferenceKindImage references an image by name, tag and/or digest
+	ReferenceKindImage ReferenceKind = "image"
+	// ReferenceKindConfigMap references a ConfigMap by its exact name
+	ReferenceKindConfigMap ReferenceKind = "configmap"
+	// ReferenceKindSecret references a Secret by its exact name
+	ReferenceKindSecret ReferenceKind = "secret"
+	// ReferenceKindAny matches any string value of an object containing the value. This is the legacy substring
+	// matching, which is prone to false positives, e.g. "app:1" matches "app:10".
+	ReferenceKindAny ReferenceKind = "any"
+	// ReferenceKindPodImageDigest references an image digest run by a container of a Pod
+	ReferenceKindPodImageDigest ReferenceKind = "pod-image-digest"
+
+	defaultImageTag = "latest"
+)
+
+var (
+	// imageReferenceKinds are the kinds of object references that point to an image, e.g. in image change triggers
+	imageReferenceKinds = []string{"ImageStreamTag", "ImageStreamImage", "DockerImage"}
+	// configMapReferenceFields are the fields holding an object with the name of a ConfigMap
+	configMapReferenceFields = []string{"configMap", "configMapRef", "configMapKeyRef"}
+	// secretReferenceFields are the fields holding an object or a list of objects with the name of a Secret
+	secretReferenceFields = []string{"secretRef", "secretKeyRef", "imagePullSecrets"}
+)
+
+type (
+	// ReferenceKind is the kind of resource a Reference points to
+	ReferenceKind string
+	// Reference is a value that is looked up in Kubernetes objects
+	Reference struct {
+		Kind  ReferenceKind
+		Value string
+	}
+	// References holds the set of values referenced by objects, indexed by kind. Each value lists the fields
+	// referencing it (e.g. "namespace/BuildConfig/app spec.strategy.sourceStrategy.from"), if known.
+	References map[ReferenceKind]map[string][]string
+	// ImageReference is a parsed reference to an image, e.g. "registry.example.com:5000/namespace/app:tag@sha256:..."
+	ImageReference struct {
+		Registry   string
+		Repository string
+		Tag        string
+		Digest     string
+	}
+)
+
+// ParseImageReference splits an image reference into registry, repository, tag and digest. A bare digest
+// ("sha256:...") only sets the digest. The first path segment is only taken as registry if it contains a dot or a port,
+// or is "localhost".
+func ParseImageReference(value string) ImageReference {
+	reference := ImageReference{}
+	if i := strings.Index(value, "@"); i >= 0 {
+		reference.Digest = value[i+1:]
+		value = value[:i]
+	} else if strings.HasPrefix(value, "sha256:") && !strings.Contains(value, "/") {
+		reference.Digest = value
+		return reference
+	}
+	if i := strings.LastIndex(value, ":"); i > strings.LastIndex(value, "/") {
+		reference.Tag = value[i+1:]
+		value = value[:i]
+	}
+	if i := strings.Index(value, "/"); i >= 0 {
+		first := value[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			reference.Registry = first
+			value = value[i+1:]
+		}
+	}
+	reference.Repository = value
+	return reference
+}
+
+// Matches evaluates if the image reference used by an object refers to the image described by r. Only the fields
+// set in r are compared. The repository matches if it is equal or ends with "/" and the repository of r, so that
+// "app:tag" matches "registry.example.com/namespace/app:tag". A reference without tag and digest refers to "latest".
+func (r ImageReference) Matches(used ImageReference) bool {
+	if r.Repository != "" && used.Repository != r.Repository && !strings.HasSuffix(used.Repository, "/"+r.Repository) {
+		return false
+	}
+	if r.Digest != "" && used.Digest != r.Digest {
+		return false
+	}
+	if r.Tag != "" {
+		usedTag := used.Tag
+		if usedTag == "" && used.Digest == "" {
+			usedTag = defaultImageTag
+		}
+		if usedTag != r.Tag {
+			return false
+		}
+	}
+	return r.Repository != "" || r.Digest != ""
+}
+
+// Contains evaluates if the given reference is part of the references
+func (refs References) Contains(reference Reference) bool {
+	for value := range refs[reference.Kind] {
+		if reference.matches(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sources returns the sorted fields referencing the given reference, as far as they are known
+func (refs References) Sources(reference Reference) []string {
+	var sources []string
+	for value, valueSources := range refs[reference.Kind] {
+		if reference.matches(value) {
+			sources = append(sources, valueSources...)
+		}
+	}
+	sources = funk.UniqString(sources)
+	sort.Strings(sources)
+	return sources
+}
+
+func (r Reference) matches(value string) bool {
+	switch r.Kind {
+	case ReferenceKindImage:
+		return ParseImageReference(r.Value).Matches(ParseImageReference(value))
+	case ReferenceKindAny:
+		return strings.Contains(value, r.Value)
+	default:
+		return value == r.Value
+	}
+}
+
+// Values returns the sorted values of the given kind
+func (refs References) Values(kind ReferenceKind) []string {
+	values := make([]string, 0, len(refs[kind]))
+	for value := range refs[kind] {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// Merge adds all references of other
+func (refs References) Merge(other References) {
+	for kind, values := range other {
+		for value, sources := range values {
+			refs.add(kind, value)
+			for _, source := range sources {
+				refs.addSource(kind, value, source)
+			}
+		}
+	}
+}
+
+func (refs References) add(kind ReferenceKind, value string) {
+	refs.addSource(kind, value, "")
+}
+
+// addSource adds the value with the field referencing it, unless the source is empty
+func (refs References) addSource(kind ReferenceKind, value, source string) {
+	if value == "" {
+		return
+	}
+	if refs[kind] == nil {
+		refs[kind] = map[string][]string{}
+	}
+	sources := refs[kind][value]
+	if source != "" && !funk.ContainsString(sources, source) {
+		sources = append(sources, source)
+	}
+	refs[kind][value] = sources
+}
+
+// ObjectReferences collects the images, ConfigMaps and Secrets referenced by a Kubernetes object. Images are taken
+// from all "image" fields and from object references of kind ImageStreamTag, ImageStreamImage or DockerImage.
+// ConfigMaps and Secrets are taken from the fields referencing them in volumes, environment variables and image pull
+// secrets. All string values are kept as ReferenceKindAny for substring matching.
+func ObjectReferences(object interface{}) References {
+	refs := References{}
+	collectReferences("", object, refs)
+	return refs
+}
+
+func collectReferences(field string, object interface{}, refs References) {
+	switch value := object.(type) {
+	case map[string]interface{}:
+		collectNamedReferences(field, value, refs)
+		for key, child := range value {
+			collectReferences(key, child, refs)
+		}
+	case []interface{}:
+		for _, child := range value {
+			collectReferences(field, child, refs)
+		}
+	case string:
+		refs.add(ReferenceKindAny, value)
+		if field == "image" {
+			refs.add(ReferenceKindImage, value)
+		}
+	}
+}
+
+func collectNamedReferences(field string, object map[string]interface{}, refs References) {
+	name, _ := object["name"].(string)
+	switch {
+	case funk.ContainsString(configMapReferenceFields, field):
+		refs.add(ReferenceKindConfigMap, name)
+	case field == "secret":
+		// volumes use "secretName", projected volume sources use "name"
+		secretName, _ := object["secretName"].(string)
+		refs.add(ReferenceKindSecret, secretName)
+		refs.add(ReferenceKindSecret, name)
+	case funk.ContainsString(secretReferenceFields, field) || strings.HasSuffix(field, "SecretRef"):
+		refs.add(ReferenceKindSecret, name)
+	default:
+		if kind, ok := object["kind"].(string); ok && funk.ContainsString(imageReferenceKinds, kind) {
+			refs.add(ReferenceKindImage, name)
+		}
+	}
+}
+
+// UnstructuredListReferences collects the references of all objects of the list, see ObjectReferences
+func UnstructuredListReferences(unstructuredList *unstructured.UnstructuredList) References {
+	refs := References{}
+	for _, item := range unstructuredList.Items {
+		collectReferences("", item.Object, refs)
+	}
+	return refs
+}
+
+// ImageStreamSpecReferences collects the image stream tags referenced by the spec tags of the image streams in the
+// list, i.e. aliases within an image stream and tags promoted from other image streams. The status of the image
+// streams is ignored, as it lists the images of their own tags.
+func ImageStreamSpecReferences(unstructuredList *unstructured.UnstructuredList) References {
+	refs := References{}
+	for _, item := range unstructuredList.Items {
+		specTags, _, _ := unstructured.NestedSlice(item.Object, "spec", "tags")
+		for _, specTag := range specTags {
+			specTagMap, ok := specTag.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			from, _, _ := unstructured.NestedMap(specTagMap, "from")
+			tag, _ := specTagMap["name"].(string)
+			addImageObjectReference(refs, item, from, fmt.Sprintf("spec.tags[%s].from", tag), "ImageStreamTag", "ImageStreamImage")
+		}
+	}
+	return refs
+}
+
+// addImageObjectReference adds the image referenced by an object reference (e.g. "from" of an image stream tag or a
+// build strategy) of the given item, if it is of one of the given kinds. Image stream tags that only name a tag (only
+// valid within image streams) are qualified with the item's name, and with their namespace if it differs from the
+// item's. The field is kept as source of the reference.
+func addImageObjectReference(refs References, item unstructured.Unstructured, objectReference map[string]interface{}, field string, kinds ...string) {
+	kind, _ := objectReference["kind"].(string)
+	name, _ := objectReference["name"].(string)
+	if name == "" || !funk.ContainsString(kinds, kind) {
+		return
+	}
+	if kind != "DockerImage" {
+		if !strings.ContainsAny(name, ":@") {
+			// a tag of the same image stream
+			name = item.GetName() + ":" + name
+		}
+		if namespace, _ := objectReference["namespace"].(string); namespace != "" && namespace != item.GetNamespace() {
+			name = namespace + "/" + name
+		}
+	}
+	source := fmt.Sprintf("%s/%s/%s %s", item.GetNamespace(), item.GetKind(), item.GetName(), field)
+	refs.addSource(ReferenceKindImage, name, source)
+	refs.addSource(ReferenceKindAny, name, source)
+}
@@ -0,0 +1,194 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantImage ImageReference
+	}{
+		{
+			name:      "ShouldParse_ImageStreamTag",
+			value:     "app:v1.2.3",
+			wantImage: ImageReference{Repository: "app", Tag: "v1.2.3"},
+		},
+		{
+			name:      "ShouldParse_RegistryWithPort",
+			value:     "image-registry.openshift-image-registry.svc:5000/namespace/app:a3d0df2",
+			wantImage: ImageReference{Registry: "image-registry.openshift-image-registry.svc:5000", Repository: "namespace/app", Tag: "a3d0df2"},
+		},
+		{
+			name:      "ShouldParse_RepositoryWithoutRegistry",
+			value:     "namespace/app",
+			wantImage: ImageReference{Repository: "namespace/app"},
+		},
+		{
+			name:      "ShouldParse_TagAndDigest",
+			value:     "localhost/app:latest@sha256:a",
+			wantImage: ImageReference{Registry: "localhost", Repository: "app", Tag: "latest", Digest: "sha256:a"},
+		},
+		{
+			name:      "ShouldParse_BareDigest",
+			value:     "sha256:a",
+			wantImage: ImageReference{Digest: "sha256:a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantImage, ParseImageReference(tt.value))
+		})
+	}
+}
+
+func TestImageReference_Matches(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference string
+		used      string
+		want      bool
+	}{
+		{name: "ShouldMatch_SameTag", reference: "app:1", used: "registry.example.com/namespace/app:1", want: true},
+		{name: "ShouldNotMatch_TagWithSamePrefix", reference: "app:1", used: "registry.example.com/namespace/app:10"},
+		{name: "ShouldNotMatch_RepositoryWithSameSuffix", reference: "app:1", used: "registry.example.com/namespace/web-app:1"},
+		{name: "ShouldMatch_LatestIfNoTag", reference: "app:latest", used: "namespace/app", want: true},
+		{name: "ShouldMatch_Digest", reference: "app@sha256:a", used: "registry.example.com/namespace/app@sha256:a", want: true},
+		{name: "ShouldMatch_BareDigest", reference: "sha256:a", used: "registry.example.com/namespace/other@sha256:a", want: true},
+		{name: "ShouldNotMatch_TagIfOnlyDigestUsed", reference: "app:latest", used: "namespace/app@sha256:a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseImageReference(tt.reference).Matches(ParseImageReference(tt.used)))
+		})
+	}
+}
+
+func TestObjectReferences(t *testing.T) {
+	object := map[string]interface{}{
+		"kind": "DeploymentConfig",
+		"spec": map[string]interface{}{
+			"triggers": []interface{}{
+				map[string]interface{}{
+					"imageChangeParams": map[string]interface{}{
+						"from": map[string]interface{}{"kind": "ImageStreamTag", "name": "app:prod"},
+					},
+				},
+			},
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"imagePullSecrets": []interface{}{map[string]interface{}{"name": "pull-secret"}},
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "webhook",
+							"image": "registry.example.com/namespace/app:a3d0df2",
+							"envFrom": []interface{}{
+								map[string]interface{}{"configMapRef": map[string]interface{}{"name": "env"}},
+							},
+							"env": []interface{}{
+								map[string]interface{}{
+									"name": "PASSWORD",
+									"valueFrom": map[string]interface{}{
+										"secretKeyRef": map[string]interface{}{"name": "credentials", "key": "password"},
+									},
+								},
+							},
+						},
+					},
+					"volumes": []interface{}{
+						map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "web"}},
+						map[string]interface{}{"name": "certs", "secret": map[string]interface{}{"secretName": "tls"}},
+					},
+				},
+			},
+		},
+	}
+
+	refs := ObjectReferences(object)
+
+	assert.ElementsMatch(t, []string{"app:prod", "registry.example.com/namespace/app:a3d0df2"}, refs.Values(ReferenceKindImage))
+	assert.ElementsMatch(t, []string{"env", "web"}, refs.Values(ReferenceKindConfigMap))
+	assert.ElementsMatch(t, []string{"pull-secret", "credentials", "tls"}, refs.Values(ReferenceKindSecret))
+	assert.True(t, refs.Contains(Reference{Kind: ReferenceKindConfigMap, Value: "web"}))
+	assert.False(t, refs.Contains(Reference{Kind: ReferenceKindConfigMap, Value: "webhook"}))
+	assert.False(t, refs.Contains(Reference{Kind: ReferenceKindSecret, Value: "web"}))
+	assert.True(t, refs.Contains(Reference{Kind: ReferenceKindImage, Value: "app:a3d0df2"}))
+}
+
+func TestUnstructuredListReferences(t *testing.T) {
+	list := &unstructured.UnstructuredList{
+		Items: []unstructured.Unstructured{
+			{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"image": "namespace/app:10"}},
+				},
+			}},
+			{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"image": "namespace/web:1"}},
+				},
+			}},
+		},
+	}
+
+	refs := UnstructuredListReferences(list)
+
+	assert.Equal(t, []string{"namespace/app:10", "namespace/web:1"}, refs.Values(ReferenceKindImage))
+	assert.False(t, refs.Contains(Reference{Kind: ReferenceKindImage, Value: "app:1"}))
+	assert.True(t, refs.Contains(Reference{Kind: ReferenceKindImage, Value: "app:10"}))
+	assert.True(t, refs.Contains(Reference{Kind: ReferenceKindAny, Value: "app:1"}))
+}
+
+func TestImageStreamSpecReferences(t *testing.T) {
+	list := &unstructured.UnstructuredList{
+		Items: []unstructured.Unstructured{
+			{Object: map[string]interface{}{
+				"kind":     "ImageStream",
+				"metadata": map[string]interface{}{"name": "app", "namespace": "app-prod"},
+				"spec": map[string]interface{}{
+					"tags": []interface{}{
+						map[string]interface{}{"name": "latest", "from": map[string]interface{}{"kind": "ImageStreamTag", "name": "v1.4.0"}},
+						map[string]interface{}{"name": "v1.4.0", "from": map[string]interface{}{"kind": "ImageStreamTag", "name": "app:a3d0df2", "namespace": "app-ci"}},
+						map[string]interface{}{"name": "pinned", "from": map[string]interface{}{"kind": "ImageStreamImage", "name": "app@sha256:a", "namespace": "app-prod"}},
+						map[string]interface{}{"name": "external", "from": map[string]interface{}{"kind": "DockerImage", "name": "docker.io/library/app:1"}},
+					},
+				},
+				"status": map[string]interface{}{
+					"tags": []interface{}{
+						map[string]interface{}{"tag": "old", "items": []interface{}{map[string]interface{}{"image": "sha256:b"}}},
+					},
+				},
+			}},
+		},
+	}
+
+	refs := ImageStreamSpecReferences(list)
+
+	assert.Equal(t, []string{"app-ci/app:a3d0df2", "app:v1.4.0", "app@sha256:a"}, refs.Values(ReferenceKindImage))
+	assert.True(t, refs.Contains(Reference{Kind: ReferenceKindImage, Value: "app:a3d0df2"}))
+	assert.False(t, refs.Contains(Reference{Kind: ReferenceKindImage, Value: "app:1"}))
+	assert.False(t, refs.Contains(Reference{Kind: ReferenceKindImage, Value: "app@sha256:b"}))
+	assert.Equal(t, []string{"app-prod/ImageStream/app spec.tags[latest].from"}, refs.Sources(Reference{Kind: ReferenceKindImage, Value: "app:v1.4.0"}))
+}
+
+func TestReferences_Sources(t *testing.T) {
+	refs := References{}
+	refs.addSource(ReferenceKindImage, "namespace/app:1", "BuildConfig/app spec.output.to")
+	refs.addSource(ReferenceKindImage, "app:1", "ImageStream/app spec.tags[latest].from")
+	refs.addSource(ReferenceKindImage, "app:1", "ImageStream/app spec.tags[latest].from")
+	refs.add(ReferenceKindImage, "app:10")
+	other := References{ReferenceKindImage: {"app:1": {"ImageStream/web spec.tags[app].from"}}}
+	refs.Merge(other)
+
+	assert.Equal(t, []string{
+		"BuildConfig/app spec.output.to",
+		"ImageStream/app spec.tags[latest].from",
+		"ImageStream/web spec.tags[app].from",
+	}, refs.Sources(Reference{Kind: ReferenceKindImage, Value: "app:1"}))
+	assert.Empty(t, refs.Sources(Reference{Kind: ReferenceKindImage, Value: "app:10"}))
+	assert.True(t, refs.Contains(Reference{Kind: ReferenceKindImage, Value: "app:10"}))
+}
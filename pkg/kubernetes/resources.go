@@ -0,0 +1,49 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ParseResource parses a resource in the form "resource.version.group", e.g. "deployments.v1.apps". Resources of the
+// core group are given without group, e.g. "pods.v1".
+func ParseResource(value string) (schema.GroupVersionResource, error) {
+	parts := strings.SplitN(value, ".", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("incorrect resource format does not match expected \"resource.version.group\" format: %s", value)
+	}
+	resource := schema.GroupVersionResource{Resource: parts[0], Version: parts[1]}
+	if len(parts) == 3 {
+		if parts[2] == "" {
+			return schema.GroupVersionResource{}, fmt.Errorf("incorrect resource format, group is empty: %s", value)
+		}
+		resource.Group = parts[2]
+	}
+	return resource, nil
+}
+
+// ParseResources parses all given resources, see ParseResource. A value may contain several comma-separated
+// resources, as it is the case when given with an environment variable.
+func ParseResources(values []string) ([]schema.GroupVersionResource, error) {
+	resources := make([]schema.GroupVersionResource, 0, len(values))
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			resource, err := ParseResource(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+// FormatResource formats a resource in the form "resource.version.group" as understood by ParseResource
+func FormatResource(resource schema.GroupVersionResource) string {
+	if resource.Group == "" {
+		return resource.Resource + "." + resource.Version
+	}
+	return resource.Resource + "." + resource.Version + "." + resource.Group
+}
@@ -0,0 +1,61 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseResource(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		wantResource schema.GroupVersionResource
+		wantErr      bool
+	}{
+		{
+			name:         "ShouldParse_CoreResource",
+			value:        "pods.v1",
+			wantResource: schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+		},
+		{
+			name:         "ShouldParse_ResourceWithGroup",
+			value:        "services.v1.serving.knative.dev",
+			wantResource: schema.GroupVersionResource{Group: "serving.knative.dev", Version: "v1", Resource: "services"},
+		},
+		{
+			name:    "ShouldThrowError_IfVersionMissing",
+			value:   "pods",
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfGroupEmpty",
+			value:   "pods.v1.",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, err := ParseResource(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantResource, resource)
+			assert.Equal(t, tt.value, FormatResource(resource))
+		})
+	}
+}
+
+func TestParseResources_ShouldSplitCommaSeparatedValues(t *testing.T) {
+	resources, err := ParseResources([]string{"pods.v1,rollouts.v1alpha1.argoproj.io", "virtualmachines.v1.kubevirt.io"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []schema.GroupVersionResource{
+		{Version: "v1", Resource: "pods"},
+		{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"},
+		{Group: "kubevirt.io", Version: "v1", Resource: "virtualmachines"},
+	}, resources)
+}
@@ -2,8 +2,10 @@ package kubernetes
 
 import (
 	"context"
+	"regexp"
 	"strings"
 
+	"github.com/thoas/go-funk"
 	"k8s.io/client-go/dynamic"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,10 +13,34 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+var (
+	podResource = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	// ImageStreamResource is the resource of OpenShift image streams, whose spec tags may reference other image stream tags
+	ImageStreamResource = schema.GroupVersionResource{Group: "image.openshift.io", Version: "v1", Resource: "imagestreams"}
+	// BuildConfigResource is the resource of OpenShift BuildConfigs, which use image stream tags as builder images, as
+	// output and in image change triggers
+	BuildConfigResource = schema.GroupVersionResource{Group: "build.openshift.io", Version: "v1", Resource: "buildconfigs"}
+	// BuildResource is the resource of OpenShift Builds
+	BuildResource = schema.GroupVersionResource{Group: "build.openshift.io", Version: "v1", Resource: "builds"}
+	// podImageFields lists the container lists of a Pod and the fields within that may contain an image digest
+	podImageFields = []struct {
+		path  []string
+		field string
+	}{
+		{path: []string{"spec", "containers"}, field: "image"},
+		{path: []string{"spec", "initContainers"}, field: "image"},
+		{path: []string{"spec", "ephemeralContainers"}, field: "image"},
+		{path: []string{"status", "containerStatuses"}, field: "imageID"},
+		{path: []string{"status", "initContainerStatuses"}, field: "imageID"},
+		{path: []string{"status", "ephemeralContainerStatuses"}, field: "imageID"},
+	}
+	digestRegex = regexp.MustCompile(`sha256:[a-f0-9]{64}`)
+)
+
 type (
 	// Kubernetes defines the interface to interact with K8s
 	Kubernetes interface {
-		ResourceContains(ctx context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error)
+		ResourceReferences(ctx context.Context, namespace string, resource schema.GroupVersionResource) (References, error)
 	}
 	// kubernetesImpl is an implementation of the interface. (Better name? introduced for better testing support)
 	kubernetesImpl struct {
@@ -27,18 +53,35 @@ func New() Kubernetes {
 	return &kubernetesImpl{}
 }
 
-// ResourceContains evaluates if a given resource contains a given string
-func (k *kubernetesImpl) ResourceContains(ctx context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error) {
+// ResourceReferences lists all objects of a given resource and returns the references they contain. For Pods, the
+// image digests run by their containers are included as well. Image streams only reference the images of their spec
+// tags, and of the Builds only the recent ones are considered (see RecentBuilds).
+func (k *kubernetesImpl) ResourceReferences(ctx context.Context, namespace string, resource schema.GroupVersionResource) (References, error) {
 	err := k.initClient()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	objectlist, err := k.client.Resource(resource).Namespace(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return UnstructuredListContains(objectlist, value), nil
+	switch resource {
+	case ImageStreamResource:
+		return ImageStreamSpecReferences(objectlist), nil
+	case BuildResource:
+		objectlist = RecentBuilds(objectlist)
+	}
+	refs := UnstructuredListReferences(objectlist)
+	switch resource {
+	case BuildConfigResource, BuildResource:
+		refs.Merge(BuildImageReferences(objectlist))
+	case podResource:
+		for _, digest := range UnstructuredListImageDigests(objectlist) {
+			refs.add(ReferenceKindPodImageDigest, digest)
+		}
+	}
+	return refs, nil
 }
 
 func (k *kubernetesImpl) initClient() error {
@@ -92,3 +135,30 @@ func UnstructuredListContains(unstructuredList *unstructured.UnstructuredList, v
 	}
 	return false
 }
+
+// UnstructuredListImageDigests returns the unique image digests referenced by the containers of the given Pods
+func UnstructuredListImageDigests(podList *unstructured.UnstructuredList) []string {
+	var digests []string
+	for _, pod := range podList.Items {
+		for _, imageField := range podImageFields {
+			containers, found, err := unstructured.NestedSlice(pod.Object, imageField.path...)
+			if err != nil || !found {
+				continue
+			}
+			for _, container := range containers {
+				containerMap, ok := container.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				value, ok := containerMap[imageField.field].(string)
+				if !ok {
+					continue
+				}
+				if digest := digestRegex.FindString(value); digest != "" && !funk.ContainsString(digests, digest) {
+					digests = append(digests, digest)
+				}
+			}
+		}
+	}
+	return digests
+}
@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -126,3 +127,34 @@ func Test_UnstructuredListContains(t *testing.T) {
 		assert.Equal(t, testcase.expected, UnstructuredListContains(testcase.objectlist, testcase.value))
 	}
 }
+
+func Test_UnstructuredListImageDigests(t *testing.T) {
+	digestA := "sha256:" + strings.Repeat("a", 64)
+	digestB := "sha256:" + strings.Repeat("b", 64)
+	digestC := "sha256:" + strings.Repeat("c", 64)
+	podList := &unstructured.UnstructuredList{
+		Items: []unstructured.Unstructured{
+			{Object: map[string]interface{}{
+				"kind":       "Pod",
+				"apiVersion": "v1",
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "app", "image": "image-registry.openshift-image-registry.svc:5000/ns/app@" + digestA},
+						map[string]interface{}{"name": "sidecar", "image": "ns/sidecar:latest"},
+					},
+					"initContainers": []interface{}{
+						map[string]interface{}{"name": "init", "image": "ns/init@" + digestC},
+					},
+				},
+				"status": map[string]interface{}{
+					"containerStatuses": []interface{}{
+						map[string]interface{}{"name": "app", "imageID": "docker-pullable://ns/app@" + digestA},
+						map[string]interface{}{"name": "sidecar", "imageID": "ns/sidecar@" + digestB},
+					},
+				},
+			}},
+		},
+	}
+
+	assert.Equal(t, []string{digestA, digestC, digestB}, UnstructuredListImageDigests(podList))
+}
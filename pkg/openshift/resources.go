@@ -2,6 +2,7 @@ package openshift
 
 import (
 	"context"
+	"sort"
 
 	"github.com/appuio/seiso/pkg/kubernetes"
 	imagev1 "github.com/openshift/api/image/v1"
@@ -12,47 +13,259 @@ import (
 )
 
 var (
-	PredefinedResources = []schema.GroupVersionResource{
+	// DefaultResources are the resources checked for usage of images, ConfigMaps and Secrets unless configured otherwise
+	DefaultResources = []schema.GroupVersionResource{
 		{Version: "v1", Resource: "pods"},
 		{Group: "apps", Version: "v1", Resource: "statefulsets"},
 		{Group: "apps", Version: "v1", Resource: "deployments"},
 		{Group: "apps", Version: "v1", Resource: "daemonsets"},
 		{Group: "apps", Version: "v1", Resource: "replicasets"},
-		{Group: "apps.openshift.io", Version: "v1", Resource: "deploymentconfigs"},
+		DeploymentConfigResource,
 		{Group: "batch", Version: "v1", Resource: "cronjobs"},
 	}
-	helper = kubernetes.New()
+	// DeploymentConfigResource is the resource of OpenShift DeploymentConfigs
+	DeploymentConfigResource = schema.GroupVersionResource{Group: "apps.openshift.io", Version: "v1", Resource: "deploymentconfigs"}
+	// OpenShiftResources are the resources only served by OpenShift. They are skipped on other Kubernetes distributions.
+	OpenShiftResources = []schema.GroupVersionResource{
+		DeploymentConfigResource,
+		kubernetes.ImageStreamResource,
+		kubernetes.BuildConfigResource,
+		kubernetes.BuildResource,
+	}
+	// PredefinedResources are the resources checked for usage of images, ConfigMaps and Secrets
+	PredefinedResources = DefaultResources
+	// RemovedResources are the resources explicitly excluded from the usage check
+	RemovedResources []schema.GroupVersionResource
+	// SubstringMatching falls back to finding references anywhere in the string values of the resources
+	SubstringMatching = false
+	helper            = kubernetes.New()
+	usageIndex        *kubernetes.ReferenceIndex
+	// ImageUsageResources are checked for usage of images in addition to PredefinedResources, unless removed or not
+	// served by the API server: image streams (aliases and promotions in spec tags), BuildConfigs and recent Builds
+	// (builder images, outputs and triggers)
+	ImageUsageResources = []schema.GroupVersionResource{
+		kubernetes.ImageStreamResource,
+		kubernetes.BuildConfigResource,
+		kubernetes.BuildResource,
+	}
 )
 
-// GetActiveImageStreamTags retrieves the image streams tags referenced in some Kubernetes resources
-func GetActiveImageStreamTags(ctx context.Context, namespace, imageStream string, imageStreamTags []string) (activeImageStreamTags []string, funcError error) {
+// getUsageIndex returns the index of the references in the resources checked for usage, which is shared by all
+// lookups of a run
+func getUsageIndex() *kubernetes.ReferenceIndex {
+	if usageIndex == nil {
+		resources := append(append([]schema.GroupVersionResource{}, PredefinedResources...), getOptionalImageUsageResources()...)
+		usageIndex = NewReferenceIndex(helper, resources)
+	}
+	return usageIndex
+}
+
+// NewReferenceIndex creates a reference index for the resources, in which the OpenShiftResources are optional, so that
+// they are skipped if the API server does not serve them
+func NewReferenceIndex(helper kubernetes.Kubernetes, resources []schema.GroupVersionResource) *kubernetes.ReferenceIndex {
+	var required, optional []schema.GroupVersionResource
+	for _, resource := range resources {
+		if containsResource(OpenShiftResources, resource) {
+			optional = append(optional, resource)
+		} else {
+			required = append(required, resource)
+		}
+	}
+	return kubernetes.NewReferenceIndex(helper, required).WithOptionalResources(optional...)
+}
+
+// getOptionalImageUsageResources returns the ImageUsageResources that are neither part of PredefinedResources nor
+// in RemovedResources
+func getOptionalImageUsageResources() []schema.GroupVersionResource {
+	var resources []schema.GroupVersionResource
+	for _, resource := range ImageUsageResources {
+		if !containsResource(PredefinedResources, resource) && !containsResource(RemovedResources, resource) {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+func containsResource(resources []schema.GroupVersionResource, resource schema.GroupVersionResource) bool {
+	for _, r := range resources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// UsageReference returns the reference of the given kind to look up in the resources checked for usage. With
+// SubstringMatching, any string value containing the value counts as a reference.
+func UsageReference(kind kubernetes.ReferenceKind, value string) kubernetes.Reference {
+	if SubstringMatching {
+		kind = kubernetes.ReferenceKindAny
+	}
+	return kubernetes.Reference{Kind: kind, Value: value}
+}
+
+// GetActiveImageStreamTags retrieves the image streams tags referenced in some Kubernetes resources of the given
+// namespaces, metav1.NamespaceAll standing for the whole cluster. A tag counts as active if the tag itself, another tag pointing to the same image digest, or the digest
+// itself (image@sha256:...) is referenced, or if a Pod is running its digest. The digests are taken from
+// allImageStreamTags. The namespaces using each active tag and the fields referencing it, as far as they are known,
+// are logged.
+func GetActiveImageStreamTags(ctx context.Context, namespaces []string, imageStream string, allImageStreamTags []imagev1.NamedTagEventList, imageStreamTags []string) (activeImageStreamTags []string, err error) {
 	log.WithFields(log.Fields{
-		"namespace": namespace,
-		"imageName": imageStream,
-		"imageTags": imageStreamTags,
+		"namespaces": namespaces,
+		"imageName":  imageStream,
+		"imageTags":  imageStreamTags,
 	}).Debug("Looking for active images")
 	if len(imageStreamTags) == 0 {
 		return []string{}, nil
 	}
-	funk.ForEach(PredefinedResources, func(predefinedResource schema.GroupVersionResource) {
-		funk.ForEach(imageStreamTags, func(imageStreamTag string) {
-			if funk.ContainsString(activeImageStreamTags, imageStreamTag) {
+	digests := GetImageStreamTagDigests(allImageStreamTags)
+	tagReferences := make(map[string][]string, len(imageStreamTags))
+	var references []string
+	for _, imageStreamTag := range imageStreamTags {
+		tagReferences[imageStreamTag] = buildImageReferences(imageStream, imageStreamTag, digests)
+		references = append(references, tagReferences[imageStreamTag]...)
+	}
+	references = funk.UniqString(references)
+
+	index := getUsageIndex()
+	usages := make(map[string][]string, len(imageStreamTags))
+	sources := make(map[string][]string, len(imageStreamTags))
+	for _, namespace := range namespaces {
+		var activeReferences []string
+		referenceSources := map[string][]string{}
+		for _, reference := range references {
+			usageReference := UsageReference(kubernetes.ReferenceKindImage, reference)
+			contains, err := index.Contains(ctx, namespace, usageReference)
+			if err != nil {
+				return nil, err
+			}
+			if contains {
+				activeReferences = append(activeReferences, reference)
+				if referenceSources[reference], err = index.Sources(ctx, namespace, usageReference); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		runningDigests, err := index.ImageDigests(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, imageStreamTag := range imageStreamTags {
+			if isActiveImageStreamTag(imageStreamTag, digests[imageStreamTag], tagReferences[imageStreamTag], activeReferences, runningDigests) {
+				usages[imageStreamTag] = append(usages[imageStreamTag], displayNamespace(namespace))
+				for _, reference := range tagReferences[imageStreamTag] {
+					sources[imageStreamTag] = append(sources[imageStreamTag], referenceSources[reference]...)
+				}
+			}
+		}
+	}
+
+	for _, imageStreamTag := range imageStreamTags {
+		if usingNamespaces, ok := usages[imageStreamTag]; ok {
+			fields := log.Fields{
+				"imageTag":   BuildImageStreamTagName(imageStream, imageStreamTag),
+				"namespaces": usingNamespaces,
+			}
+			if len(sources[imageStreamTag]) > 0 {
+				fields["referencedBy"] = funk.UniqString(sources[imageStreamTag])
+			}
+			log.WithFields(fields).Info("Image tag is in use")
+			activeImageStreamTags = append(activeImageStreamTags, imageStreamTag)
+		}
+	}
+	return activeImageStreamTags, nil
+}
+
+// displayNamespace returns the namespace for logging, metav1.NamespaceAll being shown as "*"
+func displayNamespace(namespace string) string {
+	if namespace == metav1.NamespaceAll {
+		return "*"
+	}
+	return namespace
+}
+
+func isActiveImageStreamTag(imageStreamTag, digest string, references, activeReferences, runningDigests []string) bool {
+	if digest != "" && funk.ContainsString(runningDigests, digest) {
+		log.WithFields(log.Fields{
+			"imageTag": imageStreamTag,
+			"digest":   digest,
+		}).Debug("Found running image digest")
+		return true
+	}
+	for _, reference := range references {
+		if funk.ContainsString(activeReferences, reference) {
+			log.WithFields(log.Fields{
+				"imageTag":  imageStreamTag,
+				"reference": reference,
+			}).Debug("Found active image reference")
+			return true
+		}
+	}
+	return false
+}
+
+// GetActiveImageDigests retrieves the image digests referenced in some Kubernetes resources or run by a Pod in any
+// of the given namespaces
+func GetActiveImageDigests(ctx context.Context, namespaces []string, digests []string) (activeDigests []string, err error) {
+	log.WithFields(log.Fields{
+		"namespaces": namespaces,
+		"digests":    digests,
+	}).Debug("Looking for active image digests")
+	index := getUsageIndex()
+	for _, namespace := range namespaces {
+		runningDigests, err := index.ImageDigests(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, digest := range digests {
+			if funk.ContainsString(activeDigests, digest) {
 				// already marked as existing, skip this
-				return
+				continue
 			}
-			image := BuildImageStreamTagName(imageStream, imageStreamTag)
-			contains, err := helper.ResourceContains(ctx, namespace, image, predefinedResource)
+			contains, err := index.Contains(ctx, namespace, UsageReference(kubernetes.ReferenceKindImage, digest))
 			if err != nil {
-				funcError = err
-				return
+				return nil, err
 			}
-
-			if contains {
-				activeImageStreamTags = append(activeImageStreamTags, imageStreamTag)
+			if contains || funk.ContainsString(runningDigests, digest) {
+				activeDigests = append(activeDigests, digest)
 			}
-		})
-	})
-	return activeImageStreamTags, funcError
+		}
+	}
+	return activeDigests, nil
+}
+
+// GetImageStreamTagDigests returns the image digest each tag is currently pointing to, indexed by the tag name
+func GetImageStreamTagDigests(imageStreamTags []imagev1.NamedTagEventList) map[string]string {
+	digests := make(map[string]string, len(imageStreamTags))
+	for _, imageStreamTag := range imageStreamTags {
+		if len(imageStreamTag.Items) > 0 && imageStreamTag.Items[0].Image != "" {
+			digests[imageStreamTag.Tag] = imageStreamTag.Items[0].Image
+		}
+	}
+	return digests
+}
+
+// buildImageReferences returns all references that resolve to the same image as the given tag: the tag itself, all
+// tags with the same digest and the digest reference
+func buildImageReferences(imageStream, imageStreamTag string, digests map[string]string) []string {
+	references := []string{BuildImageStreamTagName(imageStream, imageStreamTag)}
+	digest, ok := digests[imageStreamTag]
+	if !ok {
+		return references
+	}
+	var aliases []string
+	for tag, tagDigest := range digests {
+		if tagDigest == digest && tag != imageStreamTag {
+			aliases = append(aliases, tag)
+		}
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		references = append(references, BuildImageStreamTagName(imageStream, alias))
+	}
+	return append(references, BuildImageStreamDigestName(imageStream, digest))
 }
 
 // GetImageStreamTags returns the tags of an image stream older than the specified time
@@ -81,19 +294,64 @@ func DeleteImageStreamTag(ctx context.Context, namespace, name string) error {
 	return imageclient.ImageStreamTags(namespace).Delete(ctx, name, metav1.DeleteOptions{})
 }
 
+// RemoveImageStreamTagGenerations removes the given history entries from the tags of an image stream. The entries to
+// remove are indexed by tag name. The current generation of a tag is never removed.
+func RemoveImageStreamTagGenerations(ctx context.Context, namespace, imageStreamName string, generations map[string][]imagev1.TagEvent) error {
+	imageClient, err := NewImageV1Client()
+	if err != nil {
+		return err
+	}
+
+	imageStream, err := imageClient.ImageStreams(namespace).Get(ctx, imageStreamName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	for i, imageStreamTag := range imageStream.Status.Tags {
+		toRemove, ok := generations[imageStreamTag.Tag]
+		if !ok || len(imageStreamTag.Items) == 0 {
+			continue
+		}
+		items := []imagev1.TagEvent{imageStreamTag.Items[0]}
+		for _, tagEvent := range imageStreamTag.Items[1:] {
+			if !containsTagEvent(toRemove, tagEvent) {
+				items = append(items, tagEvent)
+			}
+		}
+		imageStream.Status.Tags[i].Items = items
+	}
+
+	_, err = imageClient.ImageStreams(namespace).UpdateStatus(ctx, imageStream, metav1.UpdateOptions{})
+	return err
+}
+
+func containsTagEvent(tagEvents []imagev1.TagEvent, tagEvent imagev1.TagEvent) bool {
+	for _, candidate := range tagEvents {
+		if candidate.Image == tagEvent.Image && candidate.Generation == tagEvent.Generation && candidate.Created.Equal(&tagEvent.Created) {
+			return true
+		}
+	}
+	return false
+}
+
 // BuildImageStreamTagName combines a name of an image stream and a tag
 func BuildImageStreamTagName(imageStream string, imageStreamTag string) string {
 	return imageStream + ":" + imageStreamTag
 }
 
-// ListImageStreams lists all available image streams in a namespace
-func ListImageStreams(ctx context.Context, namespace string) ([]imagev1.ImageStream, error) {
+// BuildImageStreamDigestName combines a name of an image stream and an image digest
+func BuildImageStreamDigestName(imageStream string, digest string) string {
+	return imageStream + "@" + digest
+}
+
+// ListImageStreams lists all available image streams in a namespace matching the list options
+func ListImageStreams(ctx context.Context, namespace string, listOptions metav1.ListOptions) ([]imagev1.ImageStream, error) {
 	imageClient, err := NewImageV1Client()
 	if err != nil {
 		return nil, err
 	}
 
-	imageStreams, err := imageClient.ImageStreams(namespace).List(ctx, metav1.ListOptions{})
+	imageStreams, err := imageClient.ImageStreams(namespace).List(ctx, listOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -5,9 +5,13 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/appuio/seiso/pkg/kubernetes"
+	imagev1 "github.com/openshift/api/image/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/thoas/go-funk"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -17,20 +21,28 @@ type (
 	}
 )
 
-func (m *MockHelper) ResourceContains(_ context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error) {
-	args := m.Called(namespace, value, resource)
-	return args.Bool(0), args.Error(1)
+func (m *MockHelper) ResourceReferences(_ context.Context, namespace string, resource schema.GroupVersionResource) (kubernetes.References, error) {
+	args := m.Called(namespace, resource)
+	refs, _ := args.Get(0).(kubernetes.References)
+	return refs, args.Error(1)
 }
 
 func TestGetActiveImageStreamTags(t *testing.T) {
 	type args struct {
-		namespace       string
-		imageStream     string
-		imageStreamTags []string
+		namespace          string
+		imageStream        string
+		allImageStreamTags []imagev1.NamedTagEventList
+		imageStreamTags    []string
 	}
 	tests := []struct {
 		name                      string
 		args                      args
+		activeReferences          []string
+		usageNamespace            string
+		usageNamespaceReferences  []string
+		runningDigests            []string
+		imageStreamReferences     []string
+		unservedResources         []schema.GroupVersionResource
 		wantActiveImageStreamTags []string
 		wantErr                   bool
 		helperMock                *MockHelper
@@ -43,6 +55,7 @@ func TestGetActiveImageStreamTags(t *testing.T) {
 				imageStream:     "image",
 				imageStreamTags: []string{"inactive", "active"},
 			},
+			activeReferences:          []string{"image:active"},
 			wantActiveImageStreamTags: []string{"active"},
 			helperMock:                new(MockHelper),
 			resources: []schema.GroupVersionResource{
@@ -56,6 +69,7 @@ func TestGetActiveImageStreamTags(t *testing.T) {
 				imageStream:     "image",
 				imageStreamTags: []string{"inactive", "active"},
 			},
+			activeReferences:          []string{"image:active"},
 			wantActiveImageStreamTags: []string{"active"},
 			helperMock:                new(MockHelper),
 			resources: []schema.GroupVersionResource{
@@ -63,6 +77,128 @@ func TestGetActiveImageStreamTags(t *testing.T) {
 				{Group: "apps", Version: "v1", Resource: "deployments"},
 			},
 		},
+		{
+			name: "ShouldFilter_TagWithActiveAlias",
+			args: args{
+				namespace:   "namespace",
+				imageStream: "image",
+				allImageStreamTags: []imagev1.NamedTagEventList{
+					{Tag: "v1.4.0", Items: []imagev1.TagEvent{{Image: "sha256:a"}}},
+					{Tag: "a3d0df2", Items: []imagev1.TagEvent{{Image: "sha256:a"}}},
+					{Tag: "prod", Items: []imagev1.TagEvent{{Image: "sha256:a"}}},
+					{Tag: "inactive", Items: []imagev1.TagEvent{{Image: "sha256:b"}}},
+				},
+				imageStreamTags: []string{"a3d0df2", "inactive"},
+			},
+			activeReferences:          []string{"image:prod"},
+			wantActiveImageStreamTags: []string{"a3d0df2"},
+			helperMock:                new(MockHelper),
+		},
+		{
+			name: "ShouldFilter_TagWithActiveDigest",
+			args: args{
+				namespace:   "namespace",
+				imageStream: "image",
+				allImageStreamTags: []imagev1.NamedTagEventList{
+					{Tag: "a3d0df2", Items: []imagev1.TagEvent{{Image: "sha256:a"}}},
+					{Tag: "inactive", Items: []imagev1.TagEvent{{Image: "sha256:b"}}},
+				},
+				imageStreamTags: []string{"a3d0df2", "inactive"},
+			},
+			activeReferences:          []string{"image@sha256:a"},
+			wantActiveImageStreamTags: []string{"a3d0df2"},
+			helperMock:                new(MockHelper),
+		},
+		{
+			name: "ShouldFilter_TagActiveInUsageNamespace",
+			args: args{
+				namespace:       "app-ci",
+				imageStream:     "image",
+				imageStreamTags: []string{"inactive", "active"},
+			},
+			usageNamespace:            "app-prod",
+			usageNamespaceReferences:  []string{"image:active"},
+			wantActiveImageStreamTags: []string{"active"},
+			helperMock:                new(MockHelper),
+		},
+		{
+			name: "ShouldFilter_TagWithRunningDigest",
+			args: args{
+				namespace:   "namespace",
+				imageStream: "image",
+				allImageStreamTags: []imagev1.NamedTagEventList{
+					{Tag: "a3d0df2", Items: []imagev1.TagEvent{{Image: "sha256:a"}}},
+					{Tag: "inactive", Items: []imagev1.TagEvent{{Image: "sha256:b"}}},
+				},
+				imageStreamTags: []string{"a3d0df2", "inactive"},
+			},
+			runningDigests:            []string{"sha256:a"},
+			wantActiveImageStreamTags: []string{"a3d0df2"},
+			helperMock:                new(MockHelper),
+		},
+		{
+			name: "ShouldFilter_TagReferencedByImageStreamSpecTag",
+			args: args{
+				namespace:       "namespace",
+				imageStream:     "image",
+				imageStreamTags: []string{"inactive", "promoted"},
+			},
+			imageStreamReferences:     []string{"image:promoted"},
+			wantActiveImageStreamTags: []string{"promoted"},
+			helperMock:                new(MockHelper),
+		},
+		{
+			name: "ShouldSucceed_IfImageStreamsNotServed",
+			args: args{
+				namespace:       "namespace",
+				imageStream:     "image",
+				imageStreamTags: []string{"inactive", "active"},
+			},
+			usageNamespace:            "other",
+			activeReferences:          []string{"image:active"},
+			unservedResources:         []schema.GroupVersionResource{kubernetes.ImageStreamResource},
+			wantActiveImageStreamTags: []string{"active"},
+			helperMock:                new(MockHelper),
+		},
+		{
+			name: "ShouldSucceed_IfBuildAPINotServed",
+			args: args{
+				namespace:       "namespace",
+				imageStream:     "image",
+				imageStreamTags: []string{"inactive", "promoted", "active"},
+			},
+			usageNamespace:            "other",
+			activeReferences:          []string{"image:active"},
+			usageNamespaceReferences:  []string{"image:active"},
+			imageStreamReferences:     []string{"image:promoted"},
+			unservedResources:         []schema.GroupVersionResource{kubernetes.BuildConfigResource, kubernetes.BuildResource},
+			wantActiveImageStreamTags: []string{"promoted", "active"},
+			helperMock:                new(MockHelper),
+		},
+		{
+			name: "ShouldListResourcesOnce_WithAllNamespaces",
+			args: args{
+				namespace:       metav1.NamespaceAll,
+				imageStream:     "image",
+				imageStreamTags: []string{"inactive", "active"},
+			},
+			activeReferences:          []string{"app-prod/image:active"},
+			wantActiveImageStreamTags: []string{"active"},
+			helperMock:                new(MockHelper),
+		},
+		{
+			name: "ShouldSucceed_OnKubernetesWithoutOpenShiftAPIs",
+			args: args{
+				namespace:       "namespace",
+				imageStream:     "image",
+				imageStreamTags: []string{"inactive", "active"},
+			},
+			usageNamespace:            "other",
+			activeReferences:          []string{"image:active"},
+			unservedResources:         OpenShiftResources,
+			wantActiveImageStreamTags: []string{"active"},
+			helperMock:                new(MockHelper),
+		},
 		{
 			name: "ShouldThrowError_IfClientFails",
 			args: args{
@@ -81,25 +217,77 @@ func TestGetActiveImageStreamTags(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := context.Background()
 			helper = tt.helperMock
-			for _, resource := range PredefinedResources {
-				for _, tag := range tt.args.imageStreamTags {
-					value := funk.ContainsString(tt.wantActiveImageStreamTags, tag)
-					var err error = nil
+			usageIndex = nil
+			allResources := append(append([]schema.GroupVersionResource{}, PredefinedResources...), getOptionalImageUsageResources()...)
+			namespaces := []string{tt.args.namespace}
+			if tt.usageNamespace != "" {
+				namespaces = append(namespaces, tt.usageNamespace)
+			}
+			for _, namespace := range namespaces {
+				activeReferences := tt.activeReferences
+				if tt.usageNamespace != "" && namespace == tt.usageNamespace {
+					activeReferences = tt.usageNamespaceReferences
+				}
+				for _, resource := range allResources {
+					if funk.Contains(tt.unservedResources, resource) {
+						err := apierrors.NewNotFound(resource.GroupResource(), "")
+						tt.helperMock.On("ResourceReferences", namespace, resource).Return(nil, err)
+						continue
+					}
 					if tt.wantErr {
-						err = errors.New("client error")
+						tt.helperMock.On("ResourceReferences", namespace, resource).Return(nil, errors.New("client error"))
+						continue
 					}
-					tt.helperMock.
-						On("ResourceContains", tt.args.namespace, "image:"+tag, resource).
-						Return(value, err)
+					refs := kubernetes.References{}
+					if resource == kubernetes.ImageStreamResource {
+						for _, reference := range tt.imageStreamReferences {
+							refs.Merge(kubernetes.References{kubernetes.ReferenceKindImage: {reference: {}}})
+						}
+						tt.helperMock.On("ResourceReferences", namespace, resource).Return(refs, nil)
+						continue
+					}
+					for _, reference := range activeReferences {
+						refs.Merge(kubernetes.References{kubernetes.ReferenceKindImage: {reference: {}}})
+					}
+					if resource.Resource == "pods" {
+						for _, digest := range tt.runningDigests {
+							refs.Merge(kubernetes.References{kubernetes.ReferenceKindPodImageDigest: {digest: {}}})
+						}
+					}
+					tt.helperMock.On("ResourceReferences", namespace, resource).Return(refs, nil)
 				}
 			}
-			result, err := GetActiveImageStreamTags(ctx, tt.args.namespace, tt.args.imageStream, tt.args.imageStreamTags)
+			result, err := GetActiveImageStreamTags(ctx, namespaces, tt.args.imageStream, tt.args.allImageStreamTags, tt.args.imageStreamTags)
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
 			}
 			assert.NoError(t, err)
 			assert.Equal(t, tt.wantActiveImageStreamTags, result)
+			// unserved resources are only listed once
+			wantCalls := len(namespaces)*len(allResources) - (len(namespaces)-1)*len(tt.unservedResources)
+			tt.helperMock.AssertNumberOfCalls(t, "ResourceReferences", wantCalls)
 		})
 	}
 }
+
+func TestGetOptionalImageUsageResources(t *testing.T) {
+	defer func() {
+		PredefinedResources = DefaultResources
+		RemovedResources = nil
+	}()
+	pods := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	PredefinedResources = []schema.GroupVersionResource{pods}
+	assert.Equal(t, []schema.GroupVersionResource{kubernetes.ImageStreamResource, kubernetes.BuildConfigResource, kubernetes.BuildResource}, getOptionalImageUsageResources())
+
+	PredefinedResources = []schema.GroupVersionResource{kubernetes.BuildConfigResource, pods}
+	RemovedResources = []schema.GroupVersionResource{kubernetes.ImageStreamResource}
+	assert.Equal(t, []schema.GroupVersionResource{kubernetes.BuildResource}, getOptionalImageUsageResources())
+}
+
+func TestBuildImageReferences(t *testing.T) {
+	digests := map[string]string{"v1.4.0": "sha256:a", "prod": "sha256:a", "a3d0df2": "sha256:a", "other": "sha256:b"}
+
+	assert.Equal(t, []string{"app:a3d0df2", "app:prod", "app:v1.4.0", "app@sha256:a"}, buildImageReferences("app", "a3d0df2", digests))
+	assert.Equal(t, []string{"app:unknown"}, buildImageReferences("app", "unknown", digests))
+}
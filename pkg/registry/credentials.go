@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type (
+	// Credentials holds the username and password to authenticate against a registry
+	Credentials struct {
+		Username string
+		Password string
+	}
+	dockerConfigFile struct {
+		Auths map[string]dockerConfigAuth `json:"auths"`
+	}
+	dockerConfigAuth struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+)
+
+// IsEmpty returns true if neither username nor password is set
+func (c Credentials) IsEmpty() bool {
+	return c.Username == "" && c.Password == ""
+}
+
+// LoadCredentials reads the credentials for the host of registryURL from a docker config.json file. If configPath is
+// empty, the default location is used. A missing default file or missing entry results in empty credentials.
+func LoadCredentials(configPath, registryURL string) (Credentials, error) {
+	explicitPath := configPath != ""
+	if !explicitPath {
+		configPath = defaultDockerConfigPath()
+	}
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicitPath {
+			log.WithField("path", configPath).Debug("No docker config found, continuing without registry credentials")
+			return Credentials{}, nil
+		}
+		return Credentials{}, err
+	}
+	return parseCredentials(raw, registryURL)
+}
+
+func parseCredentials(raw []byte, registryURL string) (Credentials, error) {
+	config := dockerConfigFile{}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return Credentials{}, fmt.Errorf("could not parse docker config: %w", err)
+	}
+	host := registryHost(registryURL)
+	for key, auth := range config.Auths {
+		if registryHost(key) != host {
+			continue
+		}
+		if auth.Auth == "" {
+			return Credentials{Username: auth.Username, Password: auth.Password}, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("could not decode auth of '%s': %w", key, err)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return Credentials{}, fmt.Errorf("invalid auth format of '%s', expected \"username:password\"", key)
+		}
+		return Credentials{Username: parts[0], Password: parts[1]}, nil
+	}
+	log.WithField("registry", host).Debug("No credentials found in docker config")
+	return Credentials{}, nil
+}
+
+func defaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".docker", "config.json")
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// registryHost strips scheme and path from a registry address, e.g. "https://registry.io/v2/" becomes "registry.io"
+func registryHost(address string) string {
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return address
+	}
+	return u.Host
+}
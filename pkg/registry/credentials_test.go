@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		registryURL string
+		want        Credentials
+		wantErr     bool
+	}{
+		{
+			name:        "ShouldDecodeAuth_IfHostMatches",
+			config:      `{"auths": {"registry.example.com": {"auth": "dXNlcjpwYXNz"}}}`,
+			registryURL: "https://registry.example.com",
+			want:        Credentials{Username: "user", Password: "pass"},
+		},
+		{
+			name:        "ShouldIgnoreScheme_InConfigKey",
+			config:      `{"auths": {"https://registry.example.com/v2/": {"username": "user", "password": "pass"}}}`,
+			registryURL: "registry.example.com",
+			want:        Credentials{Username: "user", Password: "pass"},
+		},
+		{
+			name:        "ShouldReturnEmpty_IfHostNotFound",
+			config:      `{"auths": {"other.example.com": {"auth": "dXNlcjpwYXNz"}}}`,
+			registryURL: "https://registry.example.com",
+			want:        Credentials{},
+		},
+		{
+			name:        "ShouldThrowError_IfAuthInvalid",
+			config:      `{"auths": {"registry.example.com": {"auth": "invalid"}}}`,
+			registryURL: "https://registry.example.com",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseCredentials([]byte(tt.config), tt.registryURL)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
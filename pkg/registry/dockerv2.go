@@ -0,0 +1,379 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	log "github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	headerContentDigest         = "Docker-Content-Digest"
+)
+
+var (
+	manifestMediaTypes  = []string{mediaTypeDockerManifest, mediaTypeDockerManifestList, mediaTypeOCIManifest, mediaTypeOCIIndex}
+	challengeParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	linkNextRegex       = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+)
+
+type (
+	dockerV2Backend struct {
+		baseURL     *url.URL
+		client      *http.Client
+		credentials Credentials
+		tokens      map[string]string
+	}
+	tagList struct {
+		Tags []string `json:"tags"`
+	}
+	manifest struct {
+		MediaType string `json:"mediaType"`
+		Config    struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Manifests []struct {
+			Digest string `json:"digest"`
+		} `json:"manifests"`
+	}
+	imageConfig struct {
+		Created time.Time `json:"created"`
+	}
+	tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+)
+
+// NewDockerV2Backend creates a backend that talks to a registry implementing the Docker Registry HTTP API v2
+func NewDockerV2Backend(registryURL string, credentials Credentials) (Backend, error) {
+	if registryURL == "" {
+		return nil, errors.New("registry URL is required")
+	}
+	if !strings.Contains(registryURL, "://") {
+		registryURL = "https://" + registryURL
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(registryURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse registry URL: %w", err)
+	}
+	return &dockerV2Backend{
+		baseURL:     baseURL,
+		client:      http.DefaultClient,
+		credentials: credentials,
+		tokens:      map[string]string{},
+	}, nil
+}
+
+// GetImageTags lists all tags of the repository "namespace/image" and resolves their digest and creation date. Tags
+// that can't be inspected, e.g. schema1 manifests or artifacts without an image config, are logged and skipped. Tags
+// whose image config has no creation date are returned with a zero date, which the age filters treat as unknown.
+func (d *dockerV2Backend) GetImageTags(ctx context.Context, namespace, image string) ([]imagev1.NamedTagEventList, error) {
+	repository := buildRepositoryName(namespace, image)
+	tags, err := d.listTags(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	var imageTags []imagev1.NamedTagEventList
+	for _, tag := range tags {
+		digest, created, err := d.inspectTag(ctx, repository, tag)
+		if err != nil {
+			log.WithError(err).WithField("imageTag", repository+":"+tag).Warn("Could not inspect image tag, skipping it")
+			continue
+		}
+		imageTags = append(imageTags, imagev1.NamedTagEventList{
+			Tag: tag,
+			Items: []imagev1.TagEvent{{
+				Created:              metav1.NewTime(created),
+				DockerImageReference: d.baseURL.Host + "/" + repository + "@" + digest,
+				Image:                digest,
+			}},
+		})
+	}
+	return imageTags, nil
+}
+
+// DeleteImageTag deletes the manifest the tag is pointing to, unless other tags are pointing to it as well, see
+// DeleteImageTags
+func (d *dockerV2Backend) DeleteImageTag(ctx context.Context, namespace, image, tag string) error {
+	return d.DeleteImageTags(ctx, namespace, image, []string{tag})
+}
+
+// DeleteImageTags deletes the manifests the given tags are pointing to. Deleting a manifest removes all tags pointing
+// to it, so the digests of all tags of the repository are resolved first and manifests that are still referenced by a
+// tag not to be deleted are skipped. Failing deletions are logged and don't stop the deletion of the other manifests.
+func (d *dockerV2Backend) DeleteImageTags(ctx context.Context, namespace, image string, tags []string) error {
+	repository := buildRepositoryName(namespace, image)
+	allTags, err := d.listTags(ctx, repository)
+	if err != nil {
+		return err
+	}
+	digests := make(map[string]string, len(allTags))
+	for _, tag := range allTags {
+		digest, err := d.resolveDigest(ctx, repository, tag)
+		if err != nil {
+			return err
+		}
+		digests[tag] = digest
+	}
+
+	deleted := map[string]bool{}
+	for _, tag := range tags {
+		digest, ok := digests[tag]
+		if !ok {
+			log.WithField("imageTag", repository+":"+tag).Warn("Image tag does not exist anymore, skipping deletion")
+			continue
+		}
+		if remaining := tagsOfDigest(digests, digest, tags); len(remaining) > 0 {
+			log.WithFields(log.Fields{
+				"imageTag":   repository + ":" + tag,
+				"digest":     digest,
+				"sharedWith": remaining,
+			}).Warn("Image tag shares its manifest with tags that are kept, skipping deletion")
+			continue
+		}
+		if deleted[digest] {
+			continue
+		}
+		deleted[digest] = true
+		log.WithFields(log.Fields{
+			"digest":    digest,
+			"imageTags": tagsOfDigest(digests, digest, nil),
+		}).Infof("Deleting manifest of %s:%s", repository, tag)
+		resp, err := d.do(ctx, http.MethodDelete, repository, "/v2/"+repository+"/manifests/"+digest, nil)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to delete %s:%s", repository, tag)
+			continue
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// resolveDigest returns the digest of the manifest the tag is pointing to
+func (d *dockerV2Backend) resolveDigest(ctx context.Context, repository, tag string) (string, error) {
+	resp, err := d.do(ctx, http.MethodHead, repository, "/v2/"+repository+"/manifests/"+tag, manifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	digest := resp.Header.Get(headerContentDigest)
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a digest for '%s:%s'", repository, tag)
+	}
+	return digest, nil
+}
+
+// tagsOfDigest returns the sorted tags pointing to the digest, except the excluded ones
+func tagsOfDigest(digests map[string]string, digest string, excluded []string) []string {
+	var tags []string
+	for tag, tagDigest := range digests {
+		if tagDigest == digest && !funk.ContainsString(excluded, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (d *dockerV2Backend) listTags(ctx context.Context, repository string) ([]string, error) {
+	var tags []string
+	path := "/v2/" + repository + "/tags/list"
+	for path != "" {
+		resp, err := d.do(ctx, http.MethodGet, repository, path, nil)
+		if err != nil {
+			return nil, err
+		}
+		list := tagList{}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not decode tag list: %w", err)
+		}
+		tags = append(tags, list.Tags...)
+		path = nextPage(resp.Header.Get("Link"))
+	}
+	return tags, nil
+}
+
+// inspectTag returns the manifest digest and the creation date found in the image config
+func (d *dockerV2Backend) inspectTag(ctx context.Context, repository, tag string) (string, time.Time, error) {
+	m, digest, err := d.getManifest(ctx, repository, tag)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if len(m.Manifests) > 0 {
+		// For manifest lists and OCI indexes, the first platform image determines the date
+		m, _, err = d.getManifest(ctx, repository, m.Manifests[0].Digest)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	if m.Config.Digest == "" {
+		return "", time.Time{}, fmt.Errorf("unsupported manifest type '%s'", m.MediaType)
+	}
+
+	resp, err := d.do(ctx, http.MethodGet, repository, "/v2/"+repository+"/blobs/"+m.Config.Digest, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	config := imageConfig{}
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return "", time.Time{}, fmt.Errorf("could not decode image config: %w", err)
+	}
+	return digest, config.Created, nil
+}
+
+func (d *dockerV2Backend) getManifest(ctx context.Context, repository, reference string) (manifest, string, error) {
+	m := manifest{}
+	resp, err := d.do(ctx, http.MethodGet, repository, "/v2/"+repository+"/manifests/"+reference, manifestMediaTypes)
+	if err != nil {
+		return m, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return m, "", err
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return m, "", fmt.Errorf("could not decode manifest: %w", err)
+	}
+	digest := resp.Header.Get(headerContentDigest)
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+	return m, digest, nil
+}
+
+// do sends the request and answers authentication challenges of the registry. Any non-2xx response is returned as error.
+func (d *dockerV2Backend) do(ctx context.Context, method, repository, path string, accept []string) (*http.Response, error) {
+	scope := "repository:" + repository + ":pull"
+	if method == http.MethodDelete {
+		scope = "repository:" + repository + ":pull,push,delete"
+	}
+
+	resp, err := d.send(ctx, method, path, accept, scope)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		if err := d.authenticate(ctx, resp.Header.Get("WWW-Authenticate"), scope); err != nil {
+			return nil, err
+		}
+		resp, err = d.send(ctx, method, path, accept, scope)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s failed: %s", method, path, resp.Status)
+	}
+	return resp, nil
+}
+
+func (d *dockerV2Backend) send(ctx context.Context, method, path string, accept []string, scope string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, d.resolve(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if token, ok := d.tokens[scope]; ok {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if !d.credentials.IsEmpty() {
+		req.SetBasicAuth(d.credentials.Username, d.credentials.Password)
+	}
+	log.WithFields(log.Fields{
+		"method": method,
+		"url":    req.URL.String(),
+	}).Debug("Sending registry request")
+	return d.client.Do(req)
+}
+
+// authenticate fetches a bearer token for the given scope as advertised in the challenge
+func (d *dockerV2Backend) authenticate(ctx context.Context, challenge, scope string) error {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return errors.New("registry requires authentication, but no supported challenge was provided")
+	}
+	params := map[string]string{}
+	for _, match := range challengeParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid authentication realm '%s'", params["realm"])
+	}
+	query := realm.Query()
+	if service, ok := params["service"]; ok {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if !d.credentials.IsEmpty() {
+		req.SetBasicAuth(d.credentials.Username, d.credentials.Password)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not retrieve registry token: %s", resp.Status)
+	}
+	token := tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("could not decode registry token: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	d.tokens[scope] = token.Token
+	return nil
+}
+
+func (d *dockerV2Backend) resolve(path string) string {
+	if strings.Contains(path, "://") {
+		return path
+	}
+	return d.baseURL.String() + path
+}
+
+// nextPage extracts the next page from a RFC5988 Link header as returned by the tag list endpoint
+func nextPage(link string) string {
+	match := linkNextRegex.FindStringSubmatch(link)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func buildRepositoryName(namespace, image string) string {
+	return namespace + "/" + image
+}
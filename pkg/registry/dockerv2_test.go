@@ -0,0 +1,235 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testManifestDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	testConfigDigest   = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	// testUndatedConfigDigest is the digest of an image config without creation date
+	testUndatedConfigDigest = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+)
+
+type fakeRegistry struct {
+	server  *httptest.Server
+	deleted []string
+	// digests overrides the manifest digest of a tag, which is testManifestDigest by default
+	digests map[string]string
+	// manifests overrides the manifest returned for a tag
+	manifests map[string]map[string]interface{}
+}
+
+func newFakeRegistry(t *testing.T, requireToken bool) *fakeRegistry {
+	r := &fakeRegistry{digests: map[string]string{}, manifests: map[string]map[string]interface{}{}}
+	mux := http.NewServeMux()
+	authorized := func(w http.ResponseWriter, req *http.Request) bool {
+		if !requireToken || req.Header.Get("Authorization") == "Bearer secret-token" {
+			return true
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.server.URL+`/token",service="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		user, pass, ok := req.BasicAuth()
+		if !ok || user != "user" || pass != "pass" || req.URL.Query().Get("service") != "registry" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "secret-token"})
+	})
+	mux.HandleFunc("/v2/namespace/app/tags/list", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		if req.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/namespace/app/tags/list?last=v1&n=1>; rel="next"`)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "namespace/app", "tags": []string{"v1"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "namespace/app", "tags": []string{"v2"}})
+	})
+	mux.HandleFunc("/v2/namespace/app/manifests/", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		if req.Method == http.MethodDelete {
+			r.deleted = append(r.deleted, req.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		digest, ok := r.digests[path.Base(req.URL.Path)]
+		if !ok {
+			digest = testManifestDigest
+		}
+		m, ok := r.manifests[path.Base(req.URL.Path)]
+		if !ok {
+			m = map[string]interface{}{
+				"mediaType": mediaTypeDockerManifest,
+				"config":    map[string]string{"digest": testConfigDigest},
+			}
+		}
+		w.Header().Set(headerContentDigest, digest)
+		w.Header().Set("Content-Type", mediaTypeDockerManifest)
+		_ = json.NewEncoder(w).Encode(m)
+	})
+	mux.HandleFunc("/v2/namespace/app/blobs/"+testConfigDigest, func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"created": "2020-05-05T10:00:00Z"})
+	})
+	mux.HandleFunc("/v2/namespace/app/blobs/"+testUndatedConfigDigest, func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"architecture": "amd64"})
+	})
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func TestDockerV2Backend_GetImageTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		requireToken bool
+		credentials  Credentials
+		wantErr      bool
+	}{
+		{
+			name: "ShouldListTags_WithoutAuthentication",
+		},
+		{
+			name:         "ShouldListTags_WithBearerToken",
+			requireToken: true,
+			credentials:  Credentials{Username: "user", Password: "pass"},
+		},
+		{
+			name:         "ShouldThrowError_IfCredentialsInvalid",
+			requireToken: true,
+			credentials:  Credentials{Username: "user", Password: "wrong"},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeRegistry(t, tt.requireToken)
+			backend, err := NewDockerV2Backend(fake.server.URL, tt.credentials)
+			require.NoError(t, err)
+
+			tags, err := backend.GetImageTags(context.Background(), "namespace", "app")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, tags, 2)
+			assert.Equal(t, "v1", tags[0].Tag)
+			assert.Equal(t, "v2", tags[1].Tag)
+			assert.Equal(t, testManifestDigest, tags[0].Items[0].Image)
+			assert.Equal(t, time.Date(2020, 5, 5, 10, 0, 0, 0, time.UTC), tags[0].Items[0].Created.Time.UTC())
+		})
+	}
+}
+
+func TestDockerV2Backend_GetImageTags_ShouldSkipTag_IfManifestUnsupported(t *testing.T) {
+	fake := newFakeRegistry(t, false)
+	fake.manifests["v1"] = map[string]interface{}{"schemaVersion": 1, "name": "namespace/app", "tag": "v1"}
+	backend, err := NewDockerV2Backend(fake.server.URL, Credentials{})
+	require.NoError(t, err)
+
+	tags, err := backend.GetImageTags(context.Background(), "namespace", "app")
+
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, "v2", tags[0].Tag)
+}
+
+func TestDockerV2Backend_GetImageTags_ShouldReturnZeroDate_IfConfigHasNoCreationDate(t *testing.T) {
+	fake := newFakeRegistry(t, false)
+	fake.manifests["v2"] = map[string]interface{}{
+		"mediaType": mediaTypeDockerManifest,
+		"config":    map[string]string{"digest": testUndatedConfigDigest},
+	}
+	backend, err := NewDockerV2Backend(fake.server.URL, Credentials{})
+	require.NoError(t, err)
+
+	tags, err := backend.GetImageTags(context.Background(), "namespace", "app")
+
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.True(t, tags[1].Items[0].Created.IsZero())
+}
+
+func TestDockerV2Backend_DeleteImageTags(t *testing.T) {
+	otherDigest := "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	tests := []struct {
+		name        string
+		digests     map[string]string
+		tags        []string
+		wantDeleted []string
+	}{
+		{
+			name:        "ShouldDeleteManifest_IfNotSharedWithOtherTags",
+			digests:     map[string]string{"v2": otherDigest},
+			tags:        []string{"v1"},
+			wantDeleted: []string{"/v2/namespace/app/manifests/" + testManifestDigest},
+		},
+		{
+			name: "ShouldNotDeleteManifest_IfSharedWithKeptTag",
+			tags: []string{"v1"},
+		},
+		{
+			name:        "ShouldDeleteSharedManifestOnce_IfAllTagsAreDeleted",
+			tags:        []string{"v1", "v2"},
+			wantDeleted: []string{"/v2/namespace/app/manifests/" + testManifestDigest},
+		},
+		{
+			name:    "ShouldSkipTag_IfItDoesNotExist",
+			digests: map[string]string{"v2": otherDigest},
+			tags:    []string{"v3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeRegistry(t, false)
+			for tag, digest := range tt.digests {
+				fake.digests[tag] = digest
+			}
+			backend, err := NewDockerV2Backend(fake.server.URL, Credentials{})
+			require.NoError(t, err)
+
+			err = backend.(BatchDeleter).DeleteImageTags(context.Background(), "namespace", "app", tt.tags)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDeleted, fake.deleted)
+		})
+	}
+}
+
+func TestDockerV2Backend_DeleteImageTag_ShouldKeepSharedManifest(t *testing.T) {
+	fake := newFakeRegistry(t, false)
+	backend, err := NewDockerV2Backend(fake.server.URL, Credentials{})
+	require.NoError(t, err)
+
+	err = backend.DeleteImageTag(context.Background(), "namespace", "app", "v1")
+
+	assert.NoError(t, err)
+	assert.Empty(t, fake.deleted)
+}
+
+func Test_nextPage(t *testing.T) {
+	assert.Equal(t, "/v2/app/tags/list?last=a&n=1", nextPage(`</v2/app/tags/list?last=a&n=1>; rel="next"`))
+	assert.Equal(t, "", nextPage(""))
+}
@@ -0,0 +1,219 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const gitLabPageSize = 100
+
+type (
+	gitLabBackend struct {
+		baseURL      *url.URL
+		client       *http.Client
+		project      string
+		tokenHeader  string
+		token        string
+		repositories map[string]int
+	}
+	gitLabRepository struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}
+	gitLabTag struct {
+		Name      string    `json:"name"`
+		Location  string    `json:"location"`
+		Digest    string    `json:"digest"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+)
+
+// NewGitLabBackend creates a backend that talks to the container registry API of a GitLab project. If project or
+// token are empty, the GitLab CI variables CI_PROJECT_ID and CI_JOB_TOKEN are used instead.
+func NewGitLabBackend(gitLabURL, project, token string) (Backend, error) {
+	if gitLabURL == "" {
+		return nil, errors.New("GitLab URL is required")
+	}
+	if !strings.Contains(gitLabURL, "://") {
+		gitLabURL = "https://" + gitLabURL
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(gitLabURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse GitLab URL: %w", err)
+	}
+	if project == "" {
+		project = os.Getenv("CI_PROJECT_ID")
+	}
+	if project == "" {
+		return nil, errors.New("GitLab project ID is required")
+	}
+	tokenHeader := "PRIVATE-TOKEN"
+	if token == "" {
+		tokenHeader = "JOB-TOKEN"
+		token = os.Getenv("CI_JOB_TOKEN")
+	}
+	return &gitLabBackend{
+		baseURL:      baseURL,
+		client:       http.DefaultClient,
+		project:      project,
+		tokenHeader:  tokenHeader,
+		token:        token,
+		repositories: map[string]int{},
+	}, nil
+}
+
+// GetImageTags lists all tags of the registry repository with their digest and creation date
+func (g *gitLabBackend) GetImageTags(ctx context.Context, namespace, image string) ([]imagev1.NamedTagEventList, error) {
+	repositoryPath, err := g.repositoryPath(ctx, namespace, image)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []gitLabTag
+	path := fmt.Sprintf("%s/tags?per_page=%d&page=1", repositoryPath, gitLabPageSize)
+	for path != "" {
+		var page []gitLabTag
+		next, err := g.getJSON(ctx, path, &page)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, page...)
+		path = next
+	}
+
+	var imageTags []imagev1.NamedTagEventList
+	for _, tag := range tags {
+		// The list endpoint does not return the creation date, only the details do
+		details := gitLabTag{}
+		if _, err := g.getJSON(ctx, repositoryPath+"/tags/"+url.PathEscape(tag.Name), &details); err != nil {
+			return nil, fmt.Errorf("could not retrieve details of tag '%s': %w", tag.Name, err)
+		}
+		imageTags = append(imageTags, imagev1.NamedTagEventList{
+			Tag: tag.Name,
+			Items: []imagev1.TagEvent{{
+				Created:              metav1.NewTime(details.CreatedAt),
+				DockerImageReference: tag.Location,
+				Image:                details.Digest,
+			}},
+		})
+	}
+	return imageTags, nil
+}
+
+// DeleteImageTag deletes a single tag of the registry repository
+func (g *gitLabBackend) DeleteImageTag(ctx context.Context, namespace, image, tag string) error {
+	repositoryPath, err := g.repositoryPath(ctx, namespace, image)
+	if err != nil {
+		return err
+	}
+	resp, err := g.do(ctx, http.MethodDelete, repositoryPath+"/tags/"+url.PathEscape(tag))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// repositoryPath finds the registry repository of the project either by its full path "namespace/image" or its name.
+// The root repository of the project has no name and is found by the project name, i.e. the last segment of its path.
+func (g *gitLabBackend) repositoryPath(ctx context.Context, namespace, image string) (string, error) {
+	key := namespace + "/" + image
+	if id, ok := g.repositories[key]; ok {
+		return g.projectPath() + fmt.Sprintf("/registry/repositories/%d", id), nil
+	}
+
+	var repositories []gitLabRepository
+	path := fmt.Sprintf("%s/registry/repositories?per_page=%d&page=1", g.projectPath(), gitLabPageSize)
+	for path != "" {
+		var page []gitLabRepository
+		next, err := g.getJSON(ctx, path, &page)
+		if err != nil {
+			return "", err
+		}
+		repositories = append(repositories, page...)
+		path = next
+	}
+
+	id := -1
+	for _, repository := range repositories {
+		if repository.Path == key {
+			id = repository.ID
+			break
+		}
+		name := repository.Name
+		if name == "" {
+			name = repository.Path[strings.LastIndex(repository.Path, "/")+1:]
+		}
+		if name == image && id < 0 {
+			id = repository.ID
+		}
+	}
+	if id < 0 {
+		return "", fmt.Errorf("no registry repository '%s' found in GitLab project %s", key, g.project)
+	}
+	g.repositories[key] = id
+	return g.projectPath() + fmt.Sprintf("/registry/repositories/%d", id), nil
+}
+
+func (g *gitLabBackend) projectPath() string {
+	return "/api/v4/projects/" + url.PathEscape(g.project)
+}
+
+// getJSON decodes the response into target and returns the path of the next page, if any
+func (g *gitLabBackend) getJSON(ctx context.Context, path string, target interface{}) (string, error) {
+	resp, err := g.do(ctx, http.MethodGet, path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return "", fmt.Errorf("could not decode GitLab response: %w", err)
+	}
+	nextPage := resp.Header.Get("X-Next-Page")
+	if nextPage == "" {
+		return "", nil
+	}
+	next, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	query := next.Query()
+	query.Set("page", nextPage)
+	next.RawQuery = query.Encode()
+	return next.String(), nil
+}
+
+func (g *gitLabBackend) do(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL.String()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.token != "" {
+		req.Header.Set(g.tokenHeader, g.token)
+	}
+	log.WithFields(log.Fields{
+		"method": method,
+		"url":    req.URL.String(),
+	}).Debug("Sending GitLab request")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s failed: %s", method, path, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFakeGitLab(t *testing.T) (*httptest.Server, *[]string) {
+	var deleted []string
+	mux := http.NewServeMux()
+	authorized := func(w http.ResponseWriter, req *http.Request) bool {
+		if req.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("/api/v4/projects/42/registry/repositories", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id": 1, "name": "", "path": "group/sub/project"}, {"id": 2, "name": "app", "path": "group/sub/project/app"}]`))
+	})
+	mux.HandleFunc("/api/v4/projects/42/registry/repositories/2/tags", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		if req.URL.Query().Get("page") == "1" {
+			w.Header().Set("X-Next-Page", "2")
+			_, _ = w.Write([]byte(`[{"name": "a3d0df2", "location": "registry.example.com/group/project/app:a3d0df2"}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name": "v1.0.0", "location": "registry.example.com/group/project/app:v1.0.0"}]`))
+	})
+	mux.HandleFunc("/api/v4/projects/42/registry/repositories/1/tags/", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		if req.Method == http.MethodDelete {
+			deleted = append(deleted, req.URL.Path)
+		}
+	})
+	mux.HandleFunc("/api/v4/projects/42/registry/repositories/2/tags/", func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(w, req) {
+			return
+		}
+		if req.Method == http.MethodDelete {
+			deleted = append(deleted, req.URL.Path)
+			return
+		}
+		name := req.URL.Path[len("/api/v4/projects/42/registry/repositories/2/tags/"):]
+		_, _ = fmt.Fprintf(w, `{"name": "%s", "digest": "sha256:%s", "created_at": "2020-04-04T10:00:00.000+00:00"}`, name, name)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &deleted
+}
+
+func TestGitLabBackend_GetImageTags(t *testing.T) {
+	server, _ := newFakeGitLab(t)
+	backend, err := NewGitLabBackend(server.URL, "42", "secret")
+	require.NoError(t, err)
+
+	tags, err := backend.GetImageTags(context.Background(), "project", "app")
+
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "a3d0df2", tags[0].Tag)
+	assert.Equal(t, "sha256:a3d0df2", tags[0].Items[0].Image)
+	assert.Equal(t, 2020, tags[0].Items[0].Created.Year())
+	assert.Equal(t, "v1.0.0", tags[1].Tag)
+}
+
+func TestGitLabBackend_DeleteImageTag(t *testing.T) {
+	tests := []struct {
+		name        string
+		namespace   string
+		image       string
+		wantErr     bool
+		wantDeleted []string
+	}{
+		{
+			name:        "ShouldDeleteTag_IfRepositoryFoundByPath",
+			namespace:   "group/sub/project",
+			image:       "app",
+			wantDeleted: []string{"/api/v4/projects/42/registry/repositories/2/tags/a3d0df2"},
+		},
+		{
+			name:        "ShouldDeleteTag_IfRepositoryFoundByName",
+			namespace:   "namespace",
+			image:       "app",
+			wantDeleted: []string{"/api/v4/projects/42/registry/repositories/2/tags/a3d0df2"},
+		},
+		{
+			name:        "ShouldDeleteTag_IfRootRepositoryFoundByProjectName",
+			namespace:   "namespace",
+			image:       "project",
+			wantDeleted: []string{"/api/v4/projects/42/registry/repositories/1/tags/a3d0df2"},
+		},
+		{
+			name:        "ShouldDeleteTag_IfRootRepositoryFoundByPath",
+			namespace:   "group/sub",
+			image:       "project",
+			wantDeleted: []string{"/api/v4/projects/42/registry/repositories/1/tags/a3d0df2"},
+		},
+		{
+			name:      "ShouldThrowError_IfRepositoryNotFound",
+			namespace: "namespace",
+			image:     "unknown",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, deleted := newFakeGitLab(t)
+			backend, err := NewGitLabBackend(server.URL, "42", "secret")
+			require.NoError(t, err)
+
+			err = backend.DeleteImageTag(context.Background(), tt.namespace, tt.image, "a3d0df2")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDeleted, *deleted)
+		})
+	}
+}
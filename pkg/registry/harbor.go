@@ -0,0 +1,153 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const harborPageSize = 100
+
+// ErrImmutableTag is returned when trying to delete a tag that is protected by a Harbor immutability rule
+var ErrImmutableTag = errors.New("tag is immutable")
+
+type (
+	harborBackend struct {
+		baseURL     *url.URL
+		client      *http.Client
+		credentials Credentials
+	}
+	harborArtifact struct {
+		Digest   string      `json:"digest"`
+		PushTime time.Time   `json:"push_time"`
+		Tags     []harborTag `json:"tags"`
+	}
+	harborTag struct {
+		Name      string    `json:"name"`
+		PushTime  time.Time `json:"push_time"`
+		Immutable bool      `json:"immutable"`
+	}
+)
+
+// NewHarborBackend creates a backend that talks to the REST API of a Harbor instance. The namespace of an image is
+// the Harbor project, the image is the repository within the project.
+func NewHarborBackend(harborURL string, credentials Credentials) (Backend, error) {
+	if harborURL == "" {
+		return nil, errors.New("harbor URL is required")
+	}
+	if !strings.Contains(harborURL, "://") {
+		harborURL = "https://" + harborURL
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(harborURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse harbor URL: %w", err)
+	}
+	return &harborBackend{
+		baseURL:     baseURL,
+		client:      http.DefaultClient,
+		credentials: credentials,
+	}, nil
+}
+
+// GetImageTags lists the tags of all artifacts in the repository. Immutable tags are reported and left out, since
+// Harbor refuses to delete them anyway.
+func (h *harborBackend) GetImageTags(ctx context.Context, project, repository string) ([]imagev1.NamedTagEventList, error) {
+	var imageTags []imagev1.NamedTagEventList
+	path := fmt.Sprintf("%s/artifacts?with_tag=true&with_immutable_status=true&page=1&page_size=%d", h.repositoryPath(project, repository), harborPageSize)
+	for path != "" {
+		resp, err := h.do(ctx, http.MethodGet, path)
+		if err != nil {
+			return nil, err
+		}
+		var artifacts []harborArtifact
+		err = json.NewDecoder(resp.Body).Decode(&artifacts)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not decode harbor artifacts: %w", err)
+		}
+
+		for _, artifact := range artifacts {
+			for _, tag := range artifact.Tags {
+				if tag.Immutable {
+					log.WithFields(log.Fields{
+						"project":    project,
+						"repository": repository,
+						"tag":        tag.Name,
+					}).Info("Skipping immutable image tag")
+					continue
+				}
+				created := tag.PushTime
+				if created.IsZero() {
+					created = artifact.PushTime
+				}
+				imageTags = append(imageTags, imagev1.NamedTagEventList{
+					Tag: tag.Name,
+					Items: []imagev1.TagEvent{{
+						Created:              metav1.NewTime(created),
+						DockerImageReference: h.baseURL.Host + "/" + project + "/" + repository + "@" + artifact.Digest,
+						Image:                artifact.Digest,
+					}},
+				})
+			}
+		}
+		path = nextPage(resp.Header.Get("Link"))
+	}
+	return imageTags, nil
+}
+
+// DeleteImageTag removes the tag from its artifact. The artifact itself is left for Harbor's garbage collection.
+func (h *harborBackend) DeleteImageTag(ctx context.Context, project, repository, tag string) error {
+	path := fmt.Sprintf("%s/artifacts/%s/tags/%s", h.repositoryPath(project, repository), url.PathEscape(tag), url.PathEscape(tag))
+	resp, err := h.do(ctx, http.MethodDelete, path)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// repositoryPath builds the API path of a repository. Harbor expects slashes in repository names to be encoded twice.
+func (h *harborBackend) repositoryPath(project, repository string) string {
+	return fmt.Sprintf("/api/v2.0/projects/%s/repositories/%s", url.PathEscape(project), url.PathEscape(url.PathEscape(repository)))
+}
+
+func (h *harborBackend) do(ctx context.Context, method, path string) (*http.Response, error) {
+	target := path
+	if !strings.Contains(path, "://") {
+		target = h.baseURL.String() + path
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if !h.credentials.IsEmpty() {
+		req.SetBasicAuth(h.credentials.Username, h.credentials.Password)
+	}
+	log.WithFields(log.Fields{
+		"method": method,
+		"url":    target,
+	}).Debug("Sending harbor request")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		resp.Body.Close()
+		return nil, ErrImmutableTag
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s failed: %s", method, path, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,102 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFakeHarbor(t *testing.T) (*httptest.Server, *[]string) {
+	var deleted []string
+	repositoryPath := "/api/v2.0/projects/project/repositories/group%252Fapp/artifacts"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.EscapedPath() != repositoryPath {
+			handleHarborDelete(w, req, &deleted)
+			return
+		}
+		user, pass, ok := req.BasicAuth()
+		if !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if req.URL.Query().Get("page") == "1" {
+			w.Header().Set("Link", `</api/v2.0/projects/project/repositories/group%252Fapp/artifacts?page=2&page_size=1>; rel="next"`)
+			_, _ = w.Write([]byte(`[{"digest": "sha256:aaa", "push_time": "2020-01-01T00:00:00Z", "tags": [
+				{"name": "v1", "push_time": "2020-02-02T00:00:00Z"},
+				{"name": "stable", "push_time": "2020-02-02T00:00:00Z", "immutable": true}]}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"digest": "sha256:bbb", "push_time": "2020-03-03T00:00:00Z", "tags": [{"name": "v2"}]}]`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &deleted
+}
+
+func handleHarborDelete(w http.ResponseWriter, req *http.Request, deleted *[]string) {
+	if req.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if req.URL.EscapedPath() == "/api/v2.0/projects/project/repositories/group%252Fapp/artifacts/stable/tags/stable" {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	*deleted = append(*deleted, req.URL.EscapedPath())
+}
+
+func TestHarborBackend_GetImageTags(t *testing.T) {
+	server, _ := newFakeHarbor(t)
+	backend, err := NewHarborBackend(server.URL, Credentials{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	tags, err := backend.GetImageTags(context.Background(), "project", "group/app")
+
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "v1", tags[0].Tag)
+	assert.Equal(t, "sha256:aaa", tags[0].Items[0].Image)
+	assert.Equal(t, 2, int(tags[0].Items[0].Created.Month()))
+	assert.Equal(t, "v2", tags[1].Tag)
+	assert.Equal(t, "sha256:bbb", tags[1].Items[0].Image)
+	assert.Equal(t, 3, int(tags[1].Items[0].Created.Month()), "falls back to artifact push time")
+}
+
+func TestHarborBackend_DeleteImageTag(t *testing.T) {
+	tests := []struct {
+		name        string
+		tag         string
+		wantErr     error
+		wantDeleted []string
+	}{
+		{
+			name:        "ShouldDeleteTag",
+			tag:         "v1",
+			wantDeleted: []string{"/api/v2.0/projects/project/repositories/group%252Fapp/artifacts/v1/tags/v1"},
+		},
+		{
+			name:    "ShouldThrowError_IfTagImmutable",
+			tag:     "stable",
+			wantErr: ErrImmutableTag,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, deleted := newFakeHarbor(t)
+			backend, err := NewHarborBackend(server.URL, Credentials{Username: "user", Password: "pass"})
+			require.NoError(t, err)
+
+			err = backend.DeleteImageTag(context.Background(), "project", "group/app", tt.tag)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDeleted, *deleted)
+		})
+	}
+}
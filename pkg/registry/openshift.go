@@ -0,0 +1,25 @@
+package registry
+
+import (
+	"context"
+
+	"github.com/appuio/seiso/pkg/openshift"
+	imagev1 "github.com/openshift/api/image/v1"
+)
+
+type openShiftBackend struct{}
+
+// NewOpenShiftBackend creates a backend that operates on image streams of the OpenShift integrated registry
+func NewOpenShiftBackend() Backend {
+	return &openShiftBackend{}
+}
+
+// GetImageTags returns the status tags of the image stream
+func (o *openShiftBackend) GetImageTags(ctx context.Context, namespace, image string) ([]imagev1.NamedTagEventList, error) {
+	return openshift.GetImageStreamTags(ctx, namespace, image)
+}
+
+// DeleteImageTag deletes the image stream tag
+func (o *openShiftBackend) DeleteImageTag(ctx context.Context, namespace, image, tag string) error {
+	return openshift.DeleteImageStreamTag(ctx, namespace, openshift.BuildImageStreamTagName(image, tag))
+}
@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/appuio/seiso/cfg"
+	imagev1 "github.com/openshift/api/image/v1"
+)
+
+// BackendOption type defines which registry implementation should be used
+type BackendOption string
+
+const (
+	// BackendOptionOpenShift uses the image streams of the OpenShift integrated registry
+	BackendOptionOpenShift BackendOption = "openshift"
+	// BackendOptionDockerV2 uses the Docker Registry HTTP API v2
+	BackendOptionDockerV2 BackendOption = "docker-v2"
+	// BackendOptionHarbor uses the REST API of Harbor
+	BackendOptionHarbor BackendOption = "harbor"
+	// BackendOptionGitLab uses the container registry API of a GitLab project
+	BackendOptionGitLab BackendOption = "gitlab"
+)
+
+type (
+	// Backend defines the interface to list and delete image tags in a registry
+	Backend interface {
+		// GetImageTags returns the tags of the given image. Each tag has exactly one item in its history, which
+		// contains the digest and the creation date of the image the tag is currently pointing to.
+		GetImageTags(ctx context.Context, namespace, image string) ([]imagev1.NamedTagEventList, error)
+		// DeleteImageTag deletes a single tag of the given image
+		DeleteImageTag(ctx context.Context, namespace, image, tag string) error
+	}
+	// BatchDeleter is implemented by backends that delete the manifest a tag is pointing to instead of the tag alone,
+	// so that they need to know all tags to delete at once to protect the manifests of the remaining tags
+	BatchDeleter interface {
+		// DeleteImageTags deletes the given tags of the image, except the ones sharing their manifest with a tag that
+		// is not deleted
+		DeleteImageTags(ctx context.Context, namespace, image string, tags []string) error
+	}
+)
+
+// IsValidBackendValue checks whether the given string is a supported registry backend
+func IsValidBackendValue(backend string) bool {
+	switch BackendOption(backend) {
+	case BackendOptionOpenShift, BackendOptionDockerV2, BackendOptionHarbor, BackendOptionGitLab:
+		return true
+	}
+	return false
+}
+
+// NewBackend creates the registry backend selected in the given config
+func NewBackend(c *cfg.RegistryConfig) (Backend, error) {
+	switch BackendOption(c.Backend) {
+	case BackendOptionOpenShift:
+		return NewOpenShiftBackend(), nil
+	case BackendOptionDockerV2:
+		credentials, err := LoadCredentials(c.DockerConfig, c.URL)
+		if err != nil {
+			return nil, fmt.Errorf("could not load registry credentials: %w", err)
+		}
+		return NewDockerV2Backend(c.URL, credentials)
+	case BackendOptionHarbor:
+		credentials, err := LoadCredentials(c.DockerConfig, c.URL)
+		if err != nil {
+			return nil, fmt.Errorf("could not load registry credentials: %w", err)
+		}
+		return NewHarborBackend(c.URL, credentials)
+	case BackendOptionGitLab:
+		return NewGitLabBackend(c.URL, c.Project, c.Token)
+	default:
+		return nil, fmt.Errorf("undefined registry backend: %s", c.Backend)
+	}
+}
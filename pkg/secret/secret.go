@@ -14,7 +14,6 @@ import (
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	core "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
@@ -46,28 +45,18 @@ func (ss SecretsService) List(ctx context.Context, listOptions metav1.ListOption
 	return secrets.Items, nil
 }
 
-func (ss SecretsService) GetUnused(ctx context.Context, namespace string, resources []v1.Secret) (unusedResources []v1.Secret, funcErr error) {
+func (ss SecretsService) GetUnused(ctx context.Context, namespace string, resources []v1.Secret) (unusedResources []v1.Secret, err error) {
 	var usedSecrets []v1.Secret
-	funk.ForEach(openshift.PredefinedResources, func(predefinedResource schema.GroupVersionResource) {
-		funk.ForEach(resources, func(secret v1.Secret) {
-
-			secretName := secret.GetName()
-
-			if funk.Contains(usedSecrets, secret) {
-				// already marked as existing, skip this
-				return
-			}
-			contains, err := ss.helper.ResourceContains(ctx, namespace, secretName, predefinedResource)
-			if err != nil {
-				funcErr = err
-				return
-			}
-
-			if contains {
-				usedSecrets = append(usedSecrets, secret)
-			}
-		})
-	})
+	index := openshift.NewReferenceIndex(ss.helper, openshift.PredefinedResources)
+	for _, secret := range resources {
+		contains, err := index.Contains(ctx, namespace, openshift.UsageReference(kubernetes.ReferenceKindSecret, secret.GetName()))
+		if err != nil {
+			return resources, err
+		}
+		if contains {
+			usedSecrets = append(usedSecrets, secret)
+		}
+	}
 
 	for _, resource := range resources {
 		if !funk.Contains(usedSecrets, resource) {
@@ -75,7 +64,7 @@ func (ss SecretsService) GetUnused(ctx context.Context, namespace string, resour
 		}
 	}
 
-	return unusedResources, funcErr
+	return unusedResources, nil
 }
 
 func (ss SecretsService) Delete(ctx context.Context, secrets []v1.Secret) error {
@@ -19,16 +19,12 @@ import (
 type HelperKubernetes struct{}
 type HelperKubernetesErr struct{}
 
-func (k HelperKubernetes) ResourceContains(_ context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error) {
-	if "nameA" == value {
-		return false, nil
-	} else {
-		return true, nil
-	}
+func (k HelperKubernetes) ResourceReferences(_ context.Context, namespace string, resource schema.GroupVersionResource) (kubernetes.References, error) {
+	return kubernetes.References{kubernetes.ReferenceKindSecret: {"nameB": {}}}, nil
 }
 
-func (k HelperKubernetesErr) ResourceContains(_ context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error) {
-	return false, errors.New("error")
+func (k HelperKubernetesErr) ResourceReferences(_ context.Context, namespace string, resource schema.GroupVersionResource) (kubernetes.References, error) {
+	return nil, errors.New("error")
 }
 
 var testNamespace = "testNamespace"